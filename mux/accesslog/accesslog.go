@@ -0,0 +1,128 @@
+// Package accesslog logs requests served by mux.Router as structured records, a record is written by Sink
+// once handler returns, e.g. as JSON lines, logfmt lines or to a log/slog handler.
+//
+//	logger := accesslog.New(accesslog.JSONSink(os.Stdout))
+//	router.UseRouteAware(logger.Middleware)
+package accesslog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mfantcy/rdx-router/mux"
+)
+
+// DefaultRequestIDHeader is the header request ID is taken from by default
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// Param is a path param of request
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Record describes a served request
+type Record struct {
+	Time   time.Time
+	Method string
+	Path   string
+
+	//Route is the template of matched route, empty if no route is matched
+	Route string
+
+	Params     []Param
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	RequestID  string
+	RemoteAddr string
+	UserAgent  string
+
+	//Panicked is set if handler panicked, the panic is left to Router.PanicFunc or http.Server
+	Panicked bool
+}
+
+// Sink writes records, it must be safe for concurrent use
+type Sink interface {
+	Log(record *Record)
+}
+
+// SinkFunc is a function used as Sink
+type SinkFunc func(record *Record)
+
+func (f SinkFunc) Log(record *Record) {
+	f(record)
+}
+
+// Logger logs requests to Sink
+type Logger struct {
+	Sink Sink
+
+	//RequestIDHeader is the header request ID is taken from
+	RequestIDHeader string
+}
+
+func New(sink Sink) *Logger {
+	return &Logger{Sink: sink, RequestIDHeader: DefaultRequestIDHeader}
+}
+
+// Middleware is a mux.MiddlewareFunc logging requests, it is meant for Router.UseRouteAware,
+// so that route and params are logged once route is matched
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder, rw := mux.NewResponseRecorder(w)
+		served := false
+		defer func() {
+			record := l.record(req, recorder, start)
+			if !served {
+				record.Panicked = true
+				if !recorder.Committed() { //response is left to recovery
+					record.Status = http.StatusInternalServerError
+				}
+			}
+			l.Sink.Log(record)
+		}()
+		next.ServeHTTP(rw, req)
+		served = true
+	})
+}
+
+// record describes req of which response is recorded by recorder
+func (l *Logger) record(req *http.Request, recorder *mux.ResponseRecorder, start time.Time) *Record {
+	record := &Record{
+		Time:       start,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Status:     statusCode(recorder),
+		Bytes:      recorder.Size(),
+		Duration:   time.Since(start),
+		RemoteAddr: req.RemoteAddr,
+		UserAgent:  req.UserAgent(),
+	}
+	if l.RequestIDHeader != "" {
+		record.RequestID = req.Header.Get(l.RequestIDHeader)
+	}
+	if matched := mux.CurrentRoute(req); matched != nil {
+		record.Route = matched.Host + matched.Pattern
+	}
+	params := mux.RequestParams(req)
+	if count := params.Count(); count > 0 {
+		record.Params = make([]Param, count)
+		for i := range record.Params {
+			record.Params[i] = Param{Name: params.Name(i), Value: params.Value(i)}
+		}
+	}
+	return record
+}
+
+// statusCode returns status code recorded by recorder, 200 if nothing is written, 101 if connection is hijacked
+func statusCode(recorder *mux.ResponseRecorder) int {
+	switch {
+	case recorder.Status() != 0:
+		return recorder.Status()
+	case recorder.Hijacked():
+		return http.StatusSwitchingProtocols
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,73 @@
+package accesslog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Middleware(t *testing.T) {
+	var records []*Record
+	logger := New(SinkFunc(func(record *Record) {
+		records = append(records, record)
+	}))
+	r := mux.NewRouter()
+	r.GET("/users/{id:int}/posts/{post}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("posts"))
+	}))
+	r.POST("/users", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	r.UseRouteAware(logger.Middleware)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7/posts/hello", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("User-Agent", "test")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Len(t, records, 3)
+	record := records[0]
+	assert.Equal(t, "GET", record.Method)
+	assert.Equal(t, "/users/7/posts/hello", record.Path)
+	assert.Equal(t, "/users/{id:int}/posts/{post}", record.Route)
+	assert.Equal(t, []Param{{Name: "id", Value: "7"}, {Name: "post", Value: "hello"}}, record.Params)
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Equal(t, int64(5), record.Bytes)
+	assert.Equal(t, "abc", record.RequestID)
+	assert.Equal(t, "test", record.UserAgent)
+	assert.Equal(t, "192.0.2.1:1234", record.RemoteAddr)
+	assert.False(t, record.Time.IsZero())
+	assert.False(t, record.Panicked)
+
+	assert.Equal(t, "/users", records[1].Route)
+	assert.Equal(t, http.StatusCreated, records[1].Status)
+	assert.Empty(t, records[1].Params)
+	assert.Empty(t, records[2].Route)
+	assert.Equal(t, http.StatusNotFound, records[2].Status)
+}
+
+func TestLogger_MiddlewarePanic(t *testing.T) {
+	var records []*Record
+	r := mux.NewRouter()
+	r.PanicFunc = func(rev interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	r.GET("/panic/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	r.UseRouteAware(New(SinkFunc(func(record *Record) {
+		records = append(records, record)
+	})).Middleware)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic/1", nil))
+	assert.Len(t, records, 1)
+	assert.True(t, records[0].Panicked)
+	assert.Equal(t, http.StatusInternalServerError, records[0].Status)
+	assert.Equal(t, "/panic/{id}", records[0].Route)
+}
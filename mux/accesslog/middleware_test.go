@@ -0,0 +1,98 @@
+package accesslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type hijackWriter struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, bufio.NewReadWriter(bufio.NewReader(w.conn), bufio.NewWriter(w.conn)), nil
+}
+
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom int
+}
+
+func (w *readerFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.ResponseRecorder, src)
+}
+
+// serve serves a request by handler with Middleware writing to w, it returns record of request
+func serve(w http.ResponseWriter, handler http.HandlerFunc) *Record {
+	var record *Record
+	logger := New(SinkFunc(func(r *Record) {
+		record = r
+	}))
+	r := mux.NewRouter()
+	r.GET("/", handler)
+	r.UseRouteAware(logger.Middleware)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return record
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	record := serve(&hijackWriter{ResponseRecorder: httptest.NewRecorder(), conn: conn}, func(w http.ResponseWriter, req *http.Request) {
+		c, _, err := w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+		assert.Equal(t, conn, c)
+		c.Close()
+	})
+	assert.Equal(t, http.StatusSwitchingProtocols, record.Status)
+
+	record = serve(httptest.NewRecorder(), func(w http.ResponseWriter, req *http.Request) {
+		_, ok := w.(http.Hijacker)
+		assert.False(t, ok)
+		_, _, err := http.NewResponseController(w).Hijack()
+		assert.ErrorIs(t, err, http.ErrNotSupported)
+	})
+	assert.Equal(t, http.StatusOK, record.Status)
+}
+
+func TestMiddleware_ReadFrom(t *testing.T) {
+	w := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+	record := serve(w, func(w http.ResponseWriter, req *http.Request) {
+		n, err := io.Copy(w, io.LimitReader(strings.NewReader("hello"), 5)) //not io.WriterTo
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+	})
+	assert.Equal(t, 1, w.readFrom)
+	assert.Equal(t, int64(5), record.Bytes)
+	assert.Equal(t, "hello", w.Body.String())
+
+	recorder := httptest.NewRecorder()
+	record = serve(recorder, func(w http.ResponseWriter, req *http.Request) {
+		_, ok := w.(io.ReaderFrom)
+		assert.False(t, ok)
+		io.Copy(w, strings.NewReader("world"))
+	})
+	assert.Equal(t, int64(5), record.Bytes)
+	assert.Equal(t, "world", recorder.Body.String())
+}
+
+func TestMiddleware_Flush(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	record := serve(recorder, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.WriteHeader(http.StatusOK)
+		assert.NoError(t, http.NewResponseController(w).Flush())
+	})
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, http.StatusAccepted, record.Status)
+}
@@ -0,0 +1,150 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonRecord is Record as written by JSONSink
+type jsonRecord struct {
+	Time       string            `json:"time"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Status     int               `json:"status"`
+	Bytes      int64             `json:"bytes"`
+	DurationMs float64           `json:"duration_ms"`
+	RequestID  string            `json:"request_id,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Panicked   bool              `json:"panicked,omitempty"`
+}
+
+// JSONSink writes records to w as JSON lines, params are written as an object by name
+func JSONSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return SinkFunc(func(record *Record) {
+		r := jsonRecord{
+			Time:       record.Time.Format(time.RFC3339Nano),
+			Method:     record.Method,
+			Path:       record.Path,
+			Route:      record.Route,
+			Status:     record.Status,
+			Bytes:      record.Bytes,
+			DurationMs: float64(record.Duration) / float64(time.Millisecond),
+			RequestID:  record.RequestID,
+			RemoteAddr: record.RemoteAddr,
+			UserAgent:  record.UserAgent,
+			Panicked:   record.Panicked,
+		}
+		for _, param := range record.Params {
+			if r.Params == nil {
+				r.Params = make(map[string]string, len(record.Params))
+			}
+			r.Params[param.Name] = param.Value
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		encoder.Encode(&r)
+	})
+}
+
+// LogfmtSink writes records to w as logfmt lines, params are written as "param.name=value"
+func LogfmtSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	return SinkFunc(func(record *Record) {
+		var b strings.Builder
+		writeLogfmt(&b, "time", record.Time.Format(time.RFC3339Nano))
+		writeLogfmt(&b, "method", record.Method)
+		writeLogfmt(&b, "path", record.Path)
+		if record.Route != "" {
+			writeLogfmt(&b, "route", record.Route)
+		}
+		for _, param := range record.Params {
+			writeLogfmt(&b, "param."+param.Name, param.Value)
+		}
+		writeLogfmt(&b, "status", strconv.Itoa(record.Status))
+		writeLogfmt(&b, "bytes", strconv.FormatInt(record.Bytes, 10))
+		writeLogfmt(&b, "duration", record.Duration.String())
+		for _, field := range [3][2]string{
+			{"request_id", record.RequestID}, {"remote_addr", record.RemoteAddr}, {"user_agent", record.UserAgent},
+		} {
+			if field[1] != "" {
+				writeLogfmt(&b, field[0], field[1])
+			}
+		}
+		if record.Panicked {
+			writeLogfmt(&b, "panicked", "true")
+		}
+		b.WriteByte('\n')
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, b.String())
+	})
+}
+
+// writeLogfmt appends key=value, value is quoted if it is empty or contains space, quote, "=" or control characters
+func writeLogfmt(b *strings.Builder, key string, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+	}) >= 0 {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(value)
+}
+
+// SlogSink writes records to handler, records of panicking handlers and of server errors are logged at error level
+func SlogSink(handler slog.Handler) Sink {
+	return SinkFunc(func(record *Record) {
+		level := slog.LevelInfo
+		if record.Panicked || record.Status >= 500 {
+			level = slog.LevelError
+		}
+		ctx := context.Background()
+		if !handler.Enabled(ctx, level) {
+			return
+		}
+		r := slog.NewRecord(record.Time, level, "request", 0)
+		r.AddAttrs(slog.String("method", record.Method), slog.String("path", record.Path))
+		if record.Route != "" {
+			r.AddAttrs(slog.String("route", record.Route))
+		}
+		if len(record.Params) > 0 {
+			params := make([]interface{}, len(record.Params))
+			for i, param := range record.Params {
+				params[i] = slog.String(param.Name, param.Value)
+			}
+			r.AddAttrs(slog.Group("params", params...))
+		}
+		r.AddAttrs(
+			slog.Int("status", record.Status),
+			slog.Int64("bytes", record.Bytes),
+			slog.Duration("duration", record.Duration),
+		)
+		if record.RequestID != "" {
+			r.AddAttrs(slog.String("request_id", record.RequestID))
+		}
+		r.AddAttrs(slog.String("remote_addr", record.RemoteAddr))
+		if record.UserAgent != "" {
+			r.AddAttrs(slog.String("user_agent", record.UserAgent))
+		}
+		if record.Panicked {
+			r.AddAttrs(slog.Bool("panicked", true))
+		}
+		handler.Handle(ctx, r)
+	})
+}
@@ -0,0 +1,61 @@
+package accesslog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRecord() *Record {
+	return &Record{
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Method:     "GET",
+		Path:       "/users/7",
+		Route:      "/users/{id}",
+		Params:     []Param{{Name: "id", Value: "7"}},
+		Status:     200,
+		Bytes:      12,
+		Duration:   1500 * time.Microsecond,
+		RequestID:  "abc",
+		RemoteAddr: "192.0.2.1:1234",
+		UserAgent:  "curl/8.0 (x86_64)",
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	JSONSink(&buf).Log(testRecord())
+	assert.JSONEq(t, `{"time":"2024-01-02T03:04:05Z","method":"GET","path":"/users/7","route":"/users/{id}",
+		"params":{"id":"7"},"status":200,"bytes":12,"duration_ms":1.5,"request_id":"abc",
+		"remote_addr":"192.0.2.1:1234","user_agent":"curl/8.0 (x86_64)"}`, buf.String())
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+}
+
+func TestLogfmtSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := LogfmtSink(&buf)
+	sink.Log(testRecord())
+	sink.Log(&Record{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Method: "GET", Path: "/a=b", Status: 500, Panicked: true})
+	assert.Equal(t, `time=2024-01-02T03:04:05Z method=GET path=/users/7 route=/users/{id} param.id=7 status=200 bytes=12 `+
+		`duration=1.5ms request_id=abc remote_addr=192.0.2.1:1234 user_agent="curl/8.0 (x86_64)"`+"\n"+
+		`time=2024-01-02T03:04:05Z method=GET path="/a=b" status=500 bytes=0 duration=0s panicked=true`+"\n", buf.String())
+}
+
+func TestSlogSink(t *testing.T) {
+	var buf bytes.Buffer
+	SlogSink(slog.NewTextHandler(&buf, nil)).Log(testRecord())
+	assert.Equal(t, `time=2024-01-02T03:04:05.000Z level=INFO msg=request method=GET path=/users/7 route=/users/{id} `+
+		`params.id=7 status=200 bytes=12 duration=1.5ms request_id=abc remote_addr=192.0.2.1:1234 `+
+		`user_agent="curl/8.0 (x86_64)"`+"\n", buf.String())
+
+	buf.Reset()
+	SlogSink(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})).Log(testRecord())
+	assert.Empty(t, buf.String())
+	record := testRecord()
+	record.Status = 503
+	SlogSink(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})).Log(record)
+	assert.Contains(t, buf.String(), "level=ERROR")
+}
@@ -0,0 +1,137 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig configures Access-Control-* headers of routes,
+// origins are matched exactly or by patterns with one "*" wildcard, e.g. "https://*.example.com", "*" allows any origin
+type CORSConfig struct {
+	AllowedOrigins []string
+
+	AllowCredentials bool
+
+	//AllowedHeaders of preflight response, headers requested by preflight are allowed if empty
+	AllowedHeaders []string
+
+	ExposedHeaders []string
+
+	//MaxAge in seconds of preflight response cache, not sent if zero
+	MaxAge int
+}
+
+// allowOrigin returns value of Access-Control-Allow-Origin for origin, empty if origin is not allowed
+func (c *CORSConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			if c.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+		if matchOrigin(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func matchOrigin(pattern string, origin string) bool {
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return strings.EqualFold(pattern, origin)
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.EqualFold(origin[:len(prefix)], prefix) && strings.EqualFold(origin[len(origin)-len(suffix):], suffix)
+}
+
+// setOriginHeaders sets headers shared by preflight and actual responses, it reports whether origin is allowed
+func (c *CORSConfig) setOriginHeaders(header http.Header, origin string) bool {
+	header.Add("Vary", "Origin")
+	allowOrigin := c.allowOrigin(origin)
+	if allowOrigin == "" {
+		return false
+	}
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	if c.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// wrap sets CORS headers of actual requests before handleFunc and middleware are called
+func (c *CORSConfig) wrap(handleFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if origin := req.Header.Get("Origin"); origin != "" {
+			if c.setOriginHeaders(w.Header(), origin) && len(c.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+			}
+		}
+		handleFunc(w, req)
+	}
+}
+
+// preflight sets headers of response to preflight request, allow is the Allow header value of route
+func (c *CORSConfig) preflight(header http.Header, req *http.Request, allow string) {
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if !c.setOriginHeaders(header, req.Header.Get("Origin")) {
+		return
+	}
+	header.Set("Access-Control-Allow-Methods", allow)
+	if len(c.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+	} else if requested := req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+	}
+}
+
+// corsConfig returns config of nearest group of method, or config of router
+func (mc *methodContext) corsConfig() *CORSConfig {
+	for g := mc.group; g != nil; g = g.parent {
+		if g.cors != nil {
+			return g.cors
+		}
+	}
+	if mc.router != nil {
+		return mc.router.cors
+	}
+	return nil
+}
+
+// preflightConfig returns config of method requested by preflight request, nil if request is not a preflight
+func (r *Route) preflightConfig(router *Router, req *http.Request) *CORSConfig {
+	method := req.Header.Get("Access-Control-Request-Method")
+	if method == "" || req.Header.Get("Origin") == "" {
+		return nil
+	}
+	methodCtx, ok := r.methods[method]
+	if !ok && method == "HEAD" && router.HandleHEAD {
+		methodCtx, ok = r.methods["GET"]
+	}
+	if !ok {
+		return nil
+	}
+	return methodCtx.corsConfig()
+}
+
+// CORS sets config of routes not configured by their groups, nil disables CORS headers,
+// it is meant to be set up before serving and is applied the same way as middleware, see Use
+func (r *Router) CORS(config *CORSConfig) {
+	r.update(func() {
+		r.cors = config
+	})
+}
+
+// CORS sets config of routes in group and its sub groups, it takes precedence over config of parent groups and router
+func (g *group) CORS(config *CORSConfig) {
+	g.router().update(func() {
+		g.cors = config
+	})
+}
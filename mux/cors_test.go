@@ -0,0 +1,92 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	assert.True(t, matchOrigin("https://example.com", "https://Example.com"))
+	assert.False(t, matchOrigin("https://example.com", "https://a.example.com"))
+	assert.True(t, matchOrigin("https://*.example.com", "https://a.example.com"))
+	assert.True(t, matchOrigin("https://*.example.com", "https://a.b.example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "https://.example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "http://a.example.com"))
+}
+
+func corsRequest(method string, path string, origin string, requestMethod string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	if requestMethod != "" {
+		req.Header.Set("Access-Control-Request-Method", requestMethod)
+		req.Header.Set("Access-Control-Request-Headers", "X-Token")
+	}
+	return req
+}
+
+func TestRouter_CORS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	r := NewRouter()
+	r.HandleHEAD = true
+	r.Handle("/items", handler, "GET", "POST")
+	r.Group("/admin", func(admin RouteRegistrar) {
+		admin.CORS(&CORSConfig{
+			AllowedOrigins:   []string{"https://admin.example.com"},
+			AllowCredentials: true,
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			MaxAge:           600,
+		})
+		admin.DELETE("/items/{id}", handler)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("GET", "/items", "https://a.example.com", ""))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	r.CORS(&CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, ExposedHeaders: []string{"X-Total"}})
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("OPTIONS", "/items", "https://a.example.com", "POST"))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "https://a.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "X-Token", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Access-Control-Request-Method", "Access-Control-Request-Headers", "Origin"}, w.Header()["Vary"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("OPTIONS", "/items", "https://evil.com", "POST"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("OPTIONS", "/items", "https://a.example.com", "PUT"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("GET", "/items", "https://a.example.com", ""))
+	assert.Equal(t, "https://a.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("OPTIONS", "/admin/items/1", "https://admin.example.com", "DELETE"))
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("DELETE", "/admin/items/1", "https://a.example.com", ""))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	r.CORS(&CORSConfig{AllowedOrigins: []string{"*"}})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, corsRequest("GET", "/items", "https://any.org", ""))
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
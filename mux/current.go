@@ -0,0 +1,44 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
+
+// MatchedRoute describes route matched by request, see CurrentRoute
+type MatchedRoute struct {
+	Node tree.NodeInterface
+
+	//Host is the host pattern of route, empty for routes not bound to any host
+	Host string
+
+	//Pattern is the full path pattern of route, e.g. "/users/{id}"
+	Pattern string
+
+	Name string
+
+	//Method is the registered method serving request, "GET" for HEAD requests served by GET handler,
+	//"*" for mounted handlers and empty for OPTIONS and method not allowed responses of router
+	Method string
+}
+
+type matchedRouteCtxKey struct{}
+
+// CurrentRoute returns route matched by request of router serving it, nil if no route is matched,
+// routes without params are only attached to requests of routers set up by Router.UseRouteAware
+func CurrentRoute(r *http.Request) *MatchedRoute {
+	if matched, ok := r.Context().Value(matchedRouteCtxKey{}).(*MatchedRoute); ok {
+		return matched
+	}
+	return nil
+}
+
+// match sets route matched by request, method and name are set once handler is chosen
+func (m *MatchedRoute) match(route *Route) {
+	m.Node, m.Host, m.Pattern = route.node, route.host, route.pattern
+}
+
+func (m *MatchedRoute) serveBy(methodCtx *methodContext, method string) {
+	m.Method, m.Name = method, methodCtx.name
+}
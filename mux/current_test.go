@@ -0,0 +1,65 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentRoute(t *testing.T) {
+	var labels []MatchedRoute
+	labelMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if matched := CurrentRoute(req); matched != nil {
+				labels = append(labels, *matched)
+			} else {
+				labels = append(labels, MatchedRoute{})
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	sub := NewRouter()
+	sub.GET("/items/{item}", handler).Name("item")
+	r := NewRouter()
+	r.HandleHEAD = true
+	r.GET("/users/{id:int}", handler).Name("user")
+	r.GET("/about", handler)
+	r.Host("{tenant}.example.com", func(rr RouteRegistrar) {
+		rr.POST("/orders", handler).Name("orders")
+	})
+	r.Mount("/shop", sub)
+	r.UseRouteAware(labelMiddleware)
+	sub.UseRouteAware(labelMiddleware)
+
+	serve := func(method string, url string) MatchedRoute {
+		labels = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, url, nil))
+		return labels[len(labels)-1]
+	}
+	matched := serve("GET", "/users/1")
+	assert.Equal(t, "/users/{id:int}", matched.Pattern)
+	assert.Equal(t, "/users/{id:int}", matched.Node.FullPathPattern())
+	assert.Equal(t, "user", matched.Name)
+	assert.Equal(t, "GET", matched.Method)
+	assert.Equal(t, "", matched.Host)
+
+	matched = serve("HEAD", "/users/1")
+	assert.Equal(t, "GET", matched.Method)
+
+	matched = serve("POST", "http://a.example.com/orders")
+	assert.Equal(t, MatchedRoute{Node: matched.Node, Host: "{tenant}.example.com", Pattern: "/orders", Name: "orders", Method: "POST"}, matched)
+
+	matched = serve("GET", "/shop/items/1")
+	assert.Equal(t, "/items/{item}", matched.Pattern)
+	assert.Equal(t, "item", matched.Name)
+	assert.Equal(t, "/shop/{...}", labels[0].Pattern)
+	assert.Equal(t, "*", labels[0].Method)
+
+	assert.Equal(t, MatchedRoute{}, serve("GET", "/missing"))
+	assert.Equal(t, "/about", serve("GET", "/about").Pattern)
+
+	assert.Nil(t, CurrentRoute(httptest.NewRequest("GET", "/", nil)))
+}
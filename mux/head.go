@@ -0,0 +1,75 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headResponseWriter discards body written by GET handler serving HEAD request, status written by handler is
+// held back until handler returns or flushes, so that Content-Length can be set from the size of discarded body,
+// other interfaces of wrapped writer are reached by http.ResponseController through Unwrap
+type headResponseWriter struct {
+	http.ResponseWriter
+	//status is held back until header is written, 0 if handler has not written it
+	status      int
+	wroteHeader bool
+	written     int
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.status != 0 {
+		return
+	}
+	if status < 200 && status != http.StatusSwitchingProtocols { //informational headers may be followed by others
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.written += len(b)
+	return len(b), nil
+}
+
+func (w *headResponseWriter) Flush() {
+	w.writeHeader()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeHeader writes the status held back, 200 if handler has not written any
+func (w *headResponseWriter) writeHeader() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *headResponseWriter) finish() {
+	if w.wroteHeader {
+		return
+	}
+	header := w.Header()
+	noBody := w.status == http.StatusSwitchingProtocols || w.status == http.StatusNoContent || w.status == http.StatusNotModified
+	if !noBody && header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" {
+		header.Set("Content-Length", strconv.Itoa(w.written))
+	}
+	w.writeHeader()
+}
+
+func serveHEAD(handleFunc http.HandlerFunc, w http.ResponseWriter, req *http.Request) {
+	hw := &headResponseWriter{ResponseWriter: w}
+	defer hw.writeHeader() //status is written even if handler panics
+	handleFunc(hw, req)
+	hw.finish()
+}
@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
+
+// hostRoutes holds routes bound to a host, the host pattern uses the same placeholder syntax as tree,
+// placeholders without regexp match one label of the host name, as do placeholders of a type such as "{id:int}"
+type hostRoutes struct {
+	pattern    string
+	regexp     *regexp.Regexp
+	params     []string
+	indexes    []int
+	matchers   []tree.MatchFunc
+	converters []tree.ConvertFunc
+	tree       tree.TrieInterface
+}
+
+// compileHostRoutes returns *RouteError if host pattern is invalid
+func compileHostRoutes(pattern string) (*hostRoutes, error) {
+	h := &hostRoutes{pattern: pattern, tree: tree.NewTree()}
+	segments, err := parsePattern(pattern)
+	if pe, ok := err.(*tree.PatternError); ok {
+		return nil, &RouteError{Host: pattern, Err: pe.Err, Detail: pe.Detail}
+	}
+	if len(segments) == 1 && !segments[0].param || len(segments) == 0 {
+		return h, nil
+	}
+	expr := "(?i)^"
+	for _, segment := range segments {
+		if !segment.param {
+			expr += regexp.QuoteMeta(segment.static)
+			continue
+		}
+		for _, name := range h.params {
+			if name != "" && name == segment.name {
+				return nil, &RouteError{Host: pattern, Err: tree.ErrDuplicateParam, Detail: "'" + name + "'"}
+			}
+		}
+		sub, convert := "[^.]+", tree.ConvertFunc(nil)
+		match := tree.Matcher(segment.regexp)
+		if match != nil {
+			convert = tree.Converter(segment.regexp)
+		} else if segment.regexp != "" {
+			sub = segment.regexp
+		} else if segment.catchAll {
+			sub = ".+"
+		}
+		expr += "(?P<p" + strconv.Itoa(len(h.params)) + ">" + sub + ")"
+		h.params = append(h.params, segment.name)
+		h.matchers = append(h.matchers, match)
+		h.converters = append(h.converters, convert)
+	}
+	rx, err := regexp.Compile(expr + "$")
+	if err != nil {
+		return nil, &RouteError{Host: pattern, Err: tree.ErrInvalidRegexp, Detail: err.Error()}
+	}
+	h.regexp = rx
+	h.indexes = make([]int, len(h.params))
+	for idx, name := range h.regexp.SubexpNames() {
+		if len(name) > 1 && name[0] == 'p' {
+			if i, err := strconv.Atoi(name[1:]); err == nil && i < len(h.indexes) {
+				h.indexes[i] = idx
+			}
+		}
+	}
+	return h, nil
+}
+
+func (h *hostRoutes) isStatic() bool {
+	return h.regexp == nil
+}
+
+// match appends host params to pairs if host matches
+func (h *hostRoutes) match(host string, pairs []tree.Pair) ([]tree.Pair, bool) {
+	if h.regexp == nil {
+		return pairs, strings.EqualFold(host, h.pattern)
+	}
+	matches := h.regexp.FindStringSubmatch(host)
+	if matches == nil {
+		return pairs, false
+	}
+	for i, match := range h.matchers {
+		if match != nil && !match(matches[h.indexes[i]]) {
+			return pairs, false
+		}
+	}
+	for i, name := range h.params {
+		pairs = append(pairs, tree.Pair{Name: name, Value: matches[h.indexes[i]]})
+	}
+	return pairs, true
+}
+
+func stripHostPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.IndexByte(host[i:], ']') < 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// hostRoutes returns routes bound to host pattern, routes of a new host are compiled but not added to table,
+// so that nothing is left in table if registering its routes fails, see addHostRoutes
+func (t *Table) hostRoutes(pattern string) (h *hostRoutes, added bool, err error) {
+	for _, h := range t.hosts {
+		if h.pattern == pattern {
+			return h, true, nil
+		}
+	}
+	h, err = compileHostRoutes(pattern)
+	return h, false, err
+}
+
+// addHostRoutes adds routes of a new host to table, static hosts are matched before host patterns
+func (t *Table) addHostRoutes(h *hostRoutes) {
+	pos := len(t.hosts)
+	if h.isStatic() {
+		for pos > 0 && !t.hosts[pos-1].isStatic() {
+			pos--
+		}
+	}
+	t.hosts = append(t.hosts, nil)
+	copy(t.hosts[pos+1:], t.hosts[pos:])
+	t.hosts[pos] = h
+}
+
+// lookup finds route of path of request in trees of matched hosts first, then in routes not bound to any host,
+// params are appended to pairs
+func (t *Table) lookup(req *http.Request, path string, fixTrailingSlash bool, pairs []tree.Pair) (interface{}, []tree.Pair, bool) {
+	if len(t.hosts) > 0 {
+		host := stripHostPort(req.Host)
+		for _, h := range t.hosts {
+			if hostPairs, ok := h.match(host, pairs); ok {
+				if rt, hostPairs, ok := h.tree.LookupAppend(path, fixTrailingSlash, hostPairs); ok {
+					return rt, hostPairs, true
+				}
+			}
+		}
+	}
+	return t.tree.LookupAppend(path, fixTrailingSlash, pairs)
+}
+
+// findCaseInsensitivePath finds registered path of path in the same order of trees as lookup
+func (t *Table) findCaseInsensitivePath(req *http.Request, path string, fixTrailingSlash bool) (string, bool) {
+	if len(t.hosts) > 0 {
+		host := stripHostPort(req.Host)
+		for _, h := range t.hosts {
+			if _, ok := h.match(host, nil); ok {
+				if fixed, ok := h.tree.FindCaseInsensitivePath(path, fixTrailingSlash); ok {
+					return fixed, true
+				}
+			}
+		}
+	}
+	return t.tree.FindCaseInsensitivePath(path, fixTrailingSlash)
+}
+
+func (r *Router) Host(host string, groupFunc func(routeRegistrar RouteRegistrar)) MiddlewareRegistrar {
+	group := newGroup("")
+	group.host = host
+	groupFunc(group)
+	r.handleGroup(group)
+	return group
+}
@@ -0,0 +1,104 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripHostPort(t *testing.T) {
+	assert.Equal(t, "example.com", stripHostPort("example.com:8080"))
+	assert.Equal(t, "example.com", stripHostPort("example.com"))
+	assert.Equal(t, "[::1]", stripHostPort("[::1]:80"))
+	assert.Equal(t, "[::1]", stripHostPort("[::1]"))
+}
+
+func TestHostRoutes_Match(t *testing.T) {
+	h, err := compileHostRoutes("example.com")
+	assert.NoError(t, err)
+	_, ok := h.match("Example.COM", nil)
+	assert.True(t, ok)
+	_, ok = h.match("a.example.com", nil)
+	assert.False(t, ok)
+
+	h, err = compileHostRoutes("{tenant}.{region:[a-z]{2}-[0-9]}.example.com")
+	assert.NoError(t, err)
+	pairs, ok := h.match("acme.eu-1.example.com", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "tenant", pairs[0].Name)
+	assert.Equal(t, "acme", pairs[0].Value)
+	assert.Equal(t, "region", pairs[1].Name)
+	assert.Equal(t, "eu-1", pairs[1].Value)
+	_, ok = h.match("a.b.eu-1.example.com", nil)
+	assert.False(t, ok)
+	_, ok = h.match("acme.eu.example.com", nil)
+	assert.False(t, ok)
+
+	h, err = compileHostRoutes("shard{n:int}.example.com")
+	assert.NoError(t, err)
+	pairs, ok = h.match("shard12.example.com", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "12", pairs[0].Value)
+	_, ok = h.match("shardx.example.com", nil)
+	assert.False(t, ok)
+
+	_, err = compileHostRoutes("{a}.{a}.example.com")
+	assert.True(t, errors.Is(err, tree.ErrDuplicateParam))
+	_, err = compileHostRoutes("{-a}.example.com")
+	assert.True(t, errors.Is(err, tree.ErrInvalidParamName))
+	assert.IsType(t, (*RouteError)(nil), err)
+}
+
+func TestRouter_Host(t *testing.T) {
+	echo := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			params := RequestParams(req)
+			w.Write([]byte(name))
+			for i := 0; i < params.Count(); i++ {
+				w.Write([]byte(" " + params.Value(i)))
+			}
+		})
+	}
+	r := NewRouter()
+	r.GET("/users/{id}", echo("default"))
+	r.Host("{tenant}.example.com", func(host RouteRegistrar) {
+		host.GET("/users/{id}", echo("tenant"))
+		host.Group("/admin", func(admin RouteRegistrar) {
+			admin.GET("/", echo("tenant-admin"))
+		})
+	})
+	r.Host("www.example.com", func(host RouteRegistrar) {
+		host.GET("/users/{id}", echo("www"))
+	})
+	r.Group("/api", func(api RouteRegistrar) {
+		api.Host("api.example.com", func(host RouteRegistrar) {
+			host.GET("/ping", echo("api"))
+		})
+	})
+
+	cases := [][]string{
+		{"other.org", "/users/1", "default 1"},
+		{"acme.example.com:8080", "/users/1", "tenant acme 1"},
+		{"acme.example.com", "/admin/", "tenant-admin acme"},
+		{"www.example.com", "/users/2", "www 2"},
+		{"api.example.com", "/api/ping", "api"},
+		{"acme.example.com", "/api/ping", "Not Found\n"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c[1], nil)
+		req.Host = c[0]
+		r.ServeHTTP(w, req)
+		assert.Equal(t, c[2], w.Body.String(), c[0]+c[1])
+	}
+
+	assert.Panics(t, func() {
+		r.Host("{id}.example.com", func(host RouteRegistrar) {
+			host.GET("/{id}", echo("conflict"))
+		})
+	})
+}
@@ -6,8 +6,15 @@ import (
 
 type ParamsHolder interface {
 	ValueOf(paramName string) string
+	Lookup(paramName string) (string, bool)
 	Value(index int) string
+	Name(index int) string
 	Count() int
+	Int(paramName string) (int, error)
+	Int64(paramName string) (int64, error)
+	Bool(paramName string) (bool, error)
+	UUID(paramName string) (UUID, error)
+	Typed(paramName string) (interface{}, error)
 }
 
 type MiddlewareFunc func(next http.Handler) http.Handler
@@ -16,18 +23,31 @@ type MiddlewareRegistrar interface {
 	Use(middleware ...MiddlewareFunc)
 }
 
+type RouteEntry interface {
+	MiddlewareRegistrar
+	Name(name string) RouteEntry
+}
+
 type RouteRegistrar interface {
-	Handle(path string, handleFunc http.Handler, httpMethod ...string) MiddlewareRegistrar
+	MiddlewareRegistrar
+
+	Handle(path string, handleFunc http.Handler, httpMethod ...string) RouteEntry
 
-	GET(path string, handleFunc http.Handler) MiddlewareRegistrar
-	POST(path string, handleFunc http.Handler) MiddlewareRegistrar
-	PUT(path string, handleFunc http.Handler) MiddlewareRegistrar
-	DELETE(path string, handleFunc http.Handler) MiddlewareRegistrar
-	OPTIONS(path string, handleFunc http.Handler) MiddlewareRegistrar
-	HEAD(path string, handleFunc http.Handler) MiddlewareRegistrar
-	PATCH(path string, handleFunc http.Handler) MiddlewareRegistrar
+	GET(path string, handleFunc http.Handler) RouteEntry
+	POST(path string, handleFunc http.Handler) RouteEntry
+	PUT(path string, handleFunc http.Handler) RouteEntry
+	DELETE(path string, handleFunc http.Handler) RouteEntry
+	OPTIONS(path string, handleFunc http.Handler) RouteEntry
+	HEAD(path string, handleFunc http.Handler) RouteEntry
+	PATCH(path string, handleFunc http.Handler) RouteEntry
 
 	Group(path string, groupFunc func(routeRegistrar RouteRegistrar)) MiddlewareRegistrar
+
+	Host(host string, groupFunc func(routeRegistrar RouteRegistrar)) MiddlewareRegistrar
+
+	Mount(prefix string, handler http.Handler) MiddlewareRegistrar
+
+	CORS(config *CORSConfig)
 }
 
 type RouteHandler interface {
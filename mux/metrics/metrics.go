@@ -0,0 +1,220 @@
+// Package metrics counts requests served by mux.Router and exposes them in Prometheus text format.
+//
+// Requests are labelled by method, status and route template as returned by mux.CurrentRoute,
+// requests not matched by any route are labelled as UnmatchedRoute, so that paths do not become labels.
+//
+//	m := metrics.New()
+//	router.UseRouteAware(m.Middleware)
+//	router.GET("/metrics", m)
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mfantcy/rdx-router/mux"
+)
+
+// UnmatchedRoute is the route label of requests not matched by any route
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod is the method label of requests with a method not defined by RFC 7231 and RFC 5789
+const OtherMethod = "OTHER"
+
+// Default buckets of request duration in seconds and response size in bytes
+var (
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets     = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+)
+
+type labels struct {
+	method string
+	route  string
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, value float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+}
+
+type series struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+// Metrics collects request count, request duration, response size and requests in flight
+type Metrics struct {
+	//Namespace prefixes names of metrics, e.g. "app" for "app_http_requests_total"
+	Namespace string
+
+	//DurationBuckets are upper bounds in seconds of request duration histogram, in increasing order
+	DurationBuckets []float64
+
+	//SizeBuckets are upper bounds in bytes of response size histogram, in increasing order
+	SizeBuckets []float64
+
+	inFlight int64
+
+	mu     sync.Mutex
+	series map[labels]*series
+}
+
+// New returns Metrics with default buckets, fields must not be changed once requests are served
+func New() *Metrics {
+	return &Metrics{
+		DurationBuckets: DefaultDurationBuckets,
+		SizeBuckets:     DefaultSizeBuckets,
+		series:          make(map[labels]*series),
+	}
+}
+
+// Middleware is a mux.MiddlewareFunc observing requests, it is meant for Router.UseRouteAware,
+// so that requests are labelled by route once it is matched.
+// Requests of handlers which panic before writing header are counted as 500, panic is left to Router.PanicFunc
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		atomic.AddInt64(&m.inFlight, 1)
+		recorder, rw := mux.NewResponseRecorder(w)
+		served := false
+		defer func() {
+			atomic.AddInt64(&m.inFlight, -1)
+			status := recorder.Status()
+			if status == 0 {
+				status = http.StatusOK
+				if !served { //response is left to recovery
+					status = http.StatusInternalServerError
+				}
+			}
+			m.observe(req, status, recorder.Size(), time.Since(start))
+		}()
+		next.ServeHTTP(rw, req)
+		served = true
+	})
+}
+
+func (m *Metrics) observe(req *http.Request, status int, size int64, duration time.Duration) {
+	l := labels{method: mux.NormalizeMethod(req.Method, OtherMethod), route: UnmatchedRoute, status: strconv.Itoa(status)}
+	if matched := mux.CurrentRoute(req); matched != nil {
+		l.route = matched.Host + matched.Pattern
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[l]
+	if !ok {
+		s = &series{}
+		m.series[l] = s
+	}
+	s.count++
+	s.duration.observe(m.DurationBuckets, duration.Seconds())
+	s.size.observe(m.SizeBuckets, float64(size))
+}
+
+// ServeHTTP writes metrics in Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(m.expose()))
+}
+
+func (m *Metrics) expose() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]labels, 0, len(m.series))
+	for l := range m.series {
+		keys = append(keys, l)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	var b strings.Builder
+	name := m.name("http_requests_total")
+	writeHeader(&b, name, "counter", "Total number of HTTP requests.")
+	for _, l := range keys {
+		b.WriteString(name + "{" + l.String() + "} " + strconv.FormatUint(m.series[l].count, 10) + "\n")
+	}
+	name = m.name("http_request_duration_seconds")
+	writeHeader(&b, name, "histogram", "Duration of HTTP requests in seconds.")
+	for _, l := range keys {
+		writeHistogram(&b, name, l, m.DurationBuckets, &m.series[l].duration, m.series[l].count)
+	}
+	name = m.name("http_response_size_bytes")
+	writeHeader(&b, name, "histogram", "Size of HTTP response bodies in bytes.")
+	for _, l := range keys {
+		writeHistogram(&b, name, l, m.SizeBuckets, &m.series[l].size, m.series[l].count)
+	}
+	name = m.name("http_requests_in_flight")
+	writeHeader(&b, name, "gauge", "Number of HTTP requests being served.")
+	b.WriteString(name + " " + strconv.FormatInt(atomic.LoadInt64(&m.inFlight), 10) + "\n")
+	return b.String()
+}
+
+func (m *Metrics) name(name string) string {
+	if m.Namespace != "" {
+		return m.Namespace + "_" + name
+	}
+	return name
+}
+
+func writeHeader(b *strings.Builder, name string, typ string, help string) {
+	b.WriteString("# HELP " + name + " " + help + "\n")
+	b.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeHistogram(b *strings.Builder, name string, l labels, buckets []float64, h *histogram, count uint64) {
+	var cumulative uint64
+	for i, bound := range buckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		b.WriteString(name + "_bucket{" + l.String() + ",le=\"" + formatFloat(bound) + "\"} " + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+	b.WriteString(name + "_bucket{" + l.String() + ",le=\"+Inf\"} " + strconv.FormatUint(count, 10) + "\n")
+	b.WriteString(name + "_sum{" + l.String() + "} " + formatFloat(h.sum) + "\n")
+	b.WriteString(name + "_count{" + l.String() + "} " + strconv.FormatUint(count, 10) + "\n")
+}
+
+func (l labels) String() string {
+	return "method=\"" + escapeLabel(l.method) + "\",route=\"" + escapeLabel(l.route) + "\",status=\"" + l.status + "\""
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.Namespace = "app"
+	m.DurationBuckets = []float64{1, 10}
+	m.SizeBuckets = []float64{1, 10}
+	r := mux.NewRouter()
+	r.GET("/users/{id:int}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("user"))
+	}))
+	r.POST("/users", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	r.UseRouteAware(m.Middleware)
+
+	for _, target := range []string{"/users/1", "/users/2", "/users/a", "/posts/1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/users", nil))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{method="GET",route="/users/{id:int}",status="200"} 2`,
+		`app_http_requests_total{method="POST",route="/users",status="201"} 1`,
+		`app_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`app_http_requests_total{method="OTHER",route="/users",status="405"} 1`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id:int}",status="200",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="/users/{id:int}",status="200"} 2`,
+		"# TYPE app_http_response_size_bytes histogram",
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/{id:int}",status="200",le="1"} 0`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/{id:int}",status="200",le="10"} 2`,
+		`app_http_response_size_bytes_sum{method="GET",route="/users/{id:int}",status="200"} 8`,
+		`app_http_response_size_bytes_bucket{method="POST",route="/users",status="201",le="1"} 1`,
+		"# TYPE app_http_requests_in_flight gauge",
+		"app_http_requests_in_flight 0",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "/posts/1")
+}
+
+func TestMetrics_InFlight(t *testing.T) {
+	m := New()
+	var exposed string
+	r := mux.NewRouter()
+	r.GET("/metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		exposed = rec.Body.String()
+	}))
+	r.UseRouteAware(m.Middleware)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, exposed, "http_requests_in_flight 1\n")
+	assert.False(t, strings.Contains(exposed, "app_"))
+}
+
+func TestMetrics_Panic(t *testing.T) {
+	m := New()
+	r := mux.NewRouter()
+	r.RecoverPanics = true
+	r.GET("/fail", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("fail")
+	}))
+	r.UseRouteAware(m.Middleware)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/fail",status="500"} 1`+"\n")
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\n`, escapeLabel("a\\b\"c\n"))
+}
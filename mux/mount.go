@@ -0,0 +1,124 @@
+package mux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountMethod registers a handler serving any method of route
+const mountMethod = "*"
+
+// mountHandler strips prefix from path of request before serving it by handler, the one registered as handler of mount
+// is copied for each route of mount with segments of prefix counted in pattern of route, see Route.compose
+type mountHandler struct {
+	handler  http.Handler
+	segments int
+	//router is the router prefix is registered to, prefix is stripped from escaped path if it routes by escaped path
+	router *Router
+}
+
+func (m *mountHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path, rawPath := stripSegments(req.URL.Path, m.segments), ""
+	if req.URL.RawPath != "" && m.router != nil && m.router.UseRawPath { //encoded "/" of prefix is not a separator
+		rawPath = stripSegments(req.URL.EscapedPath(), m.segments)
+		if unescaped, err := url.PathUnescape(rawPath); err == nil {
+			path = unescaped
+		}
+	} else if req.URL.RawPath != "" {
+		rawPath = stripSegments(req.URL.RawPath, m.segments)
+	}
+	ctx := &paramsContext{Context: req.Context(), mounted: true}
+	if params, ok := req.Context().Value(paramsCtxKey{}).(*Params); ok {
+		ctx.params = *params
+		if count := len(params.pairs) - 1; len(path) > 1 && count >= 0 {
+			//the rest of path matched by catch-all is dropped, params of prefix are left to mounted handler,
+			//params of request are kept for middleware of the mount
+			ctx.params.pairs = params.pairs[:count:count]
+			if len(ctx.params.converters) > count {
+				ctx.params.converters = ctx.params.converters[:count:count]
+			}
+		}
+	}
+	if outer, ok := req.Context().Value(mountPrefixCtxKey{}).(*mountPrefix); ok {
+		ctx.prefix = *outer
+	}
+	mounted := req.WithContext(ctx)
+	mounted.URL = new(url.URL)
+	*mounted.URL = *req.URL
+	mounted.URL.Path, mounted.URL.RawPath = path, rawPath
+	ctx.prefix.path += strings.TrimSuffix(req.URL.Path, path)
+	ctx.prefix.rawPath += strings.TrimSuffix(req.URL.EscapedPath(), mounted.URL.EscapedPath())
+	m.handler.ServeHTTP(w, mounted)
+}
+
+type mountPrefixCtxKey struct{}
+
+// mountParamsCtxKey is the key of params of mount prefix, it is only set for requests passed to mounted handler,
+// so that requests served again by a router from handlers of its routes do not take their params as prefix
+type mountParamsCtxKey struct{}
+
+// mountPrefix is the part of path stripped by mounts of request, mounted routers redirect to paths prefixed by it
+type mountPrefix struct {
+	path    string
+	rawPath string
+}
+
+// mountPaths returns patterns served by handler mounted at prefix
+func mountPaths(prefix string) []string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return []string{"/", "/{...}"}
+	}
+	return []string{prefix, prefix + "/", prefix + "/{...}"}
+}
+
+// countSegments counts segments of mount prefix in one of patterns returned by mountPaths
+func countSegments(pattern string) (segments int) {
+	pattern = strings.TrimRight(strings.TrimSuffix(pattern, "{...}"), "/")
+	parts, _ := parsePattern(pattern)
+	for _, segment := range parts {
+		for i := 0; i < len(segment.static); i++ {
+			if segment.static[i] == '/' && (i == 0 || segment.static[i-1] != '/') {
+				segments++
+			}
+		}
+	}
+	return
+}
+
+// stripSegments removes leading segments of path, the rest always starts with "/"
+func stripSegments(path string, segments int) string {
+	i := 0
+	for ; segments > 0; segments-- {
+		next := strings.IndexByte(path[i+1:], '/')
+		if next < 0 {
+			return "/"
+		}
+		i += next + 1
+	}
+	return path[i:]
+}
+
+// Mount serves requests of any method under prefix by handler, prefix is stripped from path of request,
+// params of prefix can be read by RequestParams of handler, including routes of a mounted *Router
+func (r *Router) Mount(prefix string, handler http.Handler) MiddlewareRegistrar {
+	methodCtx := newMethodContext(&mountHandler{handler: handler}, nil)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, path := range mountPaths(prefix) {
+		if err := r.handle(r.current(), "", path, methodCtx, r.Strict, mountMethod); err != nil {
+			panic(err)
+		}
+	}
+	return methodCtx
+}
+
+func (g *group) Mount(prefix string, handler http.Handler) MiddlewareRegistrar {
+	methodCtx := newMethodContext(&mountHandler{handler: handler}, g)
+	for _, path := range mountPaths(prefix) {
+		g.routes = append(g.routes, groupRoute{path: path, method: mountMethod, methodCtx: methodCtx})
+	}
+	g.methodCxtRefs = append(g.methodCxtRefs, methodCtx)
+	return methodCtx
+}
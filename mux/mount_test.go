@@ -0,0 +1,132 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripSegments(t *testing.T) {
+	assert.Equal(t, "/users/1", stripSegments("/api/users/1", 1))
+	assert.Equal(t, "/1", stripSegments("/api/users/1", 2))
+	assert.Equal(t, "/", stripSegments("/api", 1))
+	assert.Equal(t, "/", stripSegments("/api/", 1))
+	assert.Equal(t, "/api", stripSegments("/api", 0))
+}
+
+func TestCountSegments(t *testing.T) {
+	assert.Equal(t, 0, countSegments("/{...}"))
+	assert.Equal(t, 1, countSegments("/api"))
+	assert.Equal(t, 1, countSegments("/api/"))
+	assert.Equal(t, 2, countSegments("/v1//api/{...}"))
+	assert.Equal(t, 2, countSegments("/t/{tenant:[^/]+}/{...}"))
+}
+
+func TestRouter_Mount(t *testing.T) {
+	echo := func(w http.ResponseWriter, req *http.Request) {
+		params := RequestParams(req)
+		body := req.Method + " " + req.URL.Path
+		for i := 0; i < params.Count(); i++ {
+			body += " " + params.Value(i)
+		}
+		w.Write([]byte(body))
+	}
+	sub := NewRouter()
+	sub.GET("/", http.HandlerFunc(echo))
+	sub.GET("/users/{id}", http.HandlerFunc(echo))
+
+	r := NewRouter()
+	r.HandleOPTIONS = false
+	r.GET("/api/status", http.HandlerFunc(echo))
+	r.Mount("/api/", sub)
+	r.Group("/tenants/{tenant}", func(tenant RouteRegistrar) {
+		tenant.Mount("/app", sub)
+	})
+	r.Mount("/files", http.HandlerFunc(echo))
+	r.Mount("/docs[/v1]", http.HandlerFunc(echo))
+	r.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + req.URL.RawPath))
+	}))
+
+	cases := [][4]interface{}{
+		{"GET", "/api/status", 200, "GET /api/status"},
+		{"GET", "/api", 200, "GET /"},
+		{"GET", "/api/", 200, "GET /"},
+		{"GET", "/api/users/7", 200, "GET /users/7 7"},
+		{"POST", "/api/users/7", 405, "Method Not Allowed\n"},
+		{"GET", "/api/posts", 404, "Not Found\n"},
+		{"GET", "/tenants/acme/app/users/7", 200, "GET /users/7 acme 7"},
+		{"GET", "/tenants/acme/app", 200, "GET / acme"},
+		{"DELETE", "/files/a/b", 200, "DELETE /a/b"},
+		{"GET", "/docs/v1/a", 200, "GET /a"},
+		{"GET", "/docs/a", 200, "GET /a"},
+		{"GET", "/raw/a%2Fb/c", 200, "/a/b/c /a%2Fb/c"},
+		{"GET", "/other", 404, "Not Found\n"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c[0].(string), c[1].(string), nil))
+		assert.Equal(t, c[2], w.Code, c[1])
+		assert.Equal(t, c[3], w.Body.String(), c[1])
+	}
+
+	r.Strict = true
+	assert.Panics(t, func() { r.Mount("/files", sub) })
+}
+
+func TestRouter_MountMiddleware(t *testing.T) {
+	var trace []string
+	r := NewRouter()
+	r.Use(tagMiddleware("global", &trace))
+	r.Mount("/sub", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace = append(trace, "handler "+req.URL.Path)
+	})).Use(tagMiddleware("mount", &trace))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/sub/a", nil))
+	assert.Equal(t, []string{"global", "mount", "handler /a"}, trace)
+}
+
+func TestRouter_MountShouldKeepParamsOfOuterRequest(t *testing.T) {
+	var outer, inner []string
+	values := func(req *http.Request) (values []string) {
+		params := RequestParams(req)
+		for i := 0; i < params.Count(); i++ {
+			values = append(values, params.Value(i))
+		}
+		return
+	}
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req)
+			outer = values(req)
+		})
+	})
+	r.Group("/t/{tenant}", func(tenant RouteRegistrar) {
+		tenant.Mount("/app", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			inner = values(req)
+		}))
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/t/acme/app/users/1", nil))
+	assert.Equal(t, []string{"acme"}, inner)
+	assert.Equal(t, []string{"acme", "users/1"}, outer)
+}
+
+func TestRouter_ServeHTTPAgainShouldNotTakeParamsAsMountPrefix(t *testing.T) {
+	var names []string
+	r := NewRouter()
+	r.GET("/new/{x}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := RequestParams(req)
+		for i := 0; i < params.Count(); i++ {
+			names = append(names, params.Name(i)+"="+params.Value(i))
+		}
+	}))
+	r.GET("/old/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.URL.Path = "/new/5"
+		r.ServeHTTP(w, req)
+	}))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/old/abc", nil))
+	assert.Equal(t, []string{"x=5"}, names)
+}
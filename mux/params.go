@@ -2,23 +2,42 @@ package mux
 
 import (
 	"context"
-	"github.com/mfantcy/rdx-router/tree"
+	"errors"
 	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/mfantcy/rdx-router/tree"
 )
 
-const ParamCtxKey = "RequestParams"
+var ErrParamNotFound = errors.New("param not found")
+
+type ParamError struct {
+	Name string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return "param '" + e.Name + "': " + e.Err.Error()
+}
+
+// UUID is the value of "uuid" placeholders returned by Typed
+type UUID = tree.UUID
+
+// ParseUUID parses UUID in canonical form "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+func ParseUUID(s string) (UUID, error) {
+	return tree.ParseUUID(s)
+}
 
 type Params struct {
-	pairs []*tree.Pair
+	pairs []tree.Pair
+	//converters convert values of pairs, nil for params without converter, see tree.RegisterConverter
+	converters []tree.ConvertFunc
 }
 
 func (p *Params) ValueOf(paramName string) string {
-	for _, pair := range p.pairs {
-		if pair.Name == paramName {
-			return pair.Value
-		}
-	}
-	return ""
+	value, _ := p.lookup(paramName)
+	return value
 }
 
 func (p *Params) Value(index int) string {
@@ -28,23 +47,183 @@ func (p *Params) Value(index int) string {
 	return ""
 }
 
-func RequestParams(r *http.Request) ParamsHolder {
-	requestParams := r.Context().Value(ParamCtxKey)
-	if rp, ok := requestParams.(ParamsHolder); ok {
-		return rp
+// Name returns name of param at index, names are empty for unnamed placeholders
+func (p *Params) Name(index int) string {
+	if index < p.Count() {
+		return p.pairs[index].Name
 	}
-	return &Params{}
+	return ""
 }
 
 func (p *Params) Count() int {
 	return len(p.pairs)
 }
 
-func toWithRequestParams(r *http.Request, params ParamsHolder) *http.Request {
-	ctx := context.WithValue(r.Context(), ParamCtxKey, params)
+// Lookup returns value of param and whether it is matched, params of optional parts left out of path are not matched,
+// unlike params matched with empty value
+func (p *Params) Lookup(paramName string) (string, bool) {
+	return p.lookup(paramName)
+}
+
+func (p *Params) lookup(paramName string) (string, bool) {
+	for i := range p.pairs {
+		if p.pairs[i].Name == paramName {
+			return p.pairs[i].Value, true
+		}
+	}
+	return "", false
+}
+
+func (p *Params) Int(paramName string) (int, error) {
+	value, err := p.Int64(paramName)
+	if err == nil && int64(int(value)) != value {
+		err = &ParamError{paramName, strconv.ErrRange}
+	}
+	return int(value), err
+}
+
+func (p *Params) Int64(paramName string) (int64, error) {
+	value, ok := p.lookup(paramName)
+	if !ok {
+		return 0, &ParamError{paramName, ErrParamNotFound}
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &ParamError{paramName, err}
+	}
+	return i, nil
+}
+
+func (p *Params) Bool(paramName string) (bool, error) {
+	value, ok := p.lookup(paramName)
+	if !ok {
+		return false, &ParamError{paramName, ErrParamNotFound}
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ParamError{paramName, err}
+	}
+	return b, nil
+}
+
+func (p *Params) UUID(paramName string) (UUID, error) {
+	value, ok := p.lookup(paramName)
+	if !ok {
+		return UUID{}, &ParamError{paramName, ErrParamNotFound}
+	}
+	u, err := ParseUUID(value)
+	if err != nil {
+		return UUID{}, &ParamError{paramName, err}
+	}
+	return u, nil
+}
+
+// Typed returns value converted by converter of placeholder type of param, e.g. int of "{id:int}",
+// values of params without converter are returned as string
+func (p *Params) Typed(paramName string) (interface{}, error) {
+	for i := range p.pairs {
+		if p.pairs[i].Name != paramName {
+			continue
+		}
+		if i < len(p.converters) && p.converters[i] != nil {
+			value, err := p.converters[i](p.pairs[i].Value)
+			if err != nil {
+				return nil, &ParamError{paramName, err}
+			}
+			return value, nil
+		}
+		return p.pairs[i].Value, nil
+	}
+	return nil, &ParamError{paramName, ErrParamNotFound}
+}
+
+type paramsCtxKey struct{}
+
+// paramsContext carries params and matched route of request, a new one is made for each matched request,
+// so that it stays valid in requests and contexts kept by handlers after they return
+type paramsContext struct {
+	context.Context
+	params  Params
+	matched MatchedRoute
+	//prefix is set by mountHandler, it is empty for requests not mounted
+	prefix mountPrefix
+	//mounted is set by mountHandler, params are those of mount prefix then
+	mounted bool
+	//pairs backs params of routes with few params
+	pairs [4]tree.Pair
+}
+
+func newParamsContext(parent context.Context, pairs []tree.Pair, converters []tree.ConvertFunc, matched *MatchedRoute) *paramsContext {
+	ctx := &paramsContext{Context: parent, matched: *matched}
+	if len(pairs) <= len(ctx.pairs) {
+		ctx.params.pairs = append(ctx.pairs[:0], pairs...)
+	} else {
+		ctx.params.pairs = append([]tree.Pair(nil), pairs...)
+	}
+	ctx.params.converters = converters
+	return ctx
+}
+
+func (c *paramsContext) Value(key interface{}) interface{} {
+	switch key {
+	case paramsCtxKey{}:
+		return &c.params
+	case matchedRouteCtxKey{}:
+		if c.matched.Node != nil {
+			return &c.matched
+		}
+	case mountPrefixCtxKey{}:
+		if c.prefix.path != "" {
+			return &c.prefix
+		}
+	case mountParamsCtxKey{}:
+		if c.mounted {
+			return &c.params
+		}
+		return nil //params of matched route are not those of a mount prefix
+	}
+	return c.Context.Value(key)
+}
+
+// requestBuffer holds params of request while its route is looked up, params are copied to paramsContext
+// once route is matched, it is pooled and reused once request is served
+type requestBuffer struct {
+	pairs   []tree.Pair
+	matched MatchedRoute
+	writer  ResponseRecorder
+}
+
+var requestBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &requestBuffer{pairs: make([]tree.Pair, 0, 8)}
+	},
+}
+
+func acquireRequestBuffer() *requestBuffer {
+	return requestBufferPool.Get().(*requestBuffer)
+}
+
+func releaseRequestBuffer(buf *requestBuffer) {
+	buf.pairs = buf.pairs[:0]
+	buf.matched = MatchedRoute{}
+	buf.writer = ResponseRecorder{}
+	requestBufferPool.Put(buf)
+}
+
+var emptyParams = &Params{}
+
+// RequestParams returns params of matched route
+func RequestParams(r *http.Request) ParamsHolder {
+	if rp, ok := r.Context().Value(paramsCtxKey{}).(ParamsHolder); ok {
+		return rp
+	}
+	return emptyParams
+}
+
+func toWithRequestParams(r *http.Request, ctx *paramsContext) *http.Request {
 	return r.WithContext(ctx)
 }
 
-func newParams(pairs []*tree.Pair) *Params {
-	return &Params{pairs}
+func newParams(pairs []tree.Pair) *Params {
+	return &Params{pairs: pairs}
 }
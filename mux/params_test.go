@@ -1,6 +1,9 @@
 package mux
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,17 +12,17 @@ import (
 )
 
 func TestParams_newParams(t *testing.T) {
-	var p []*tree.Pair
+	var p []tree.Pair
 	ps := newParams(p)
 	assert.IsType(t, (*Params)(nil), ps)
 }
 
 func TestParams_ValueOf(t *testing.T) {
-	var p []*tree.Pair
+	var p []tree.Pair
 	ps := newParams(p)
 	assert.Empty(t, ps.ValueOf("key"))
 
-	p2 := []*tree.Pair{&tree.Pair{"abc", "cde"}, &tree.Pair{"123", "432"}}
+	p2 := []tree.Pair{{Name: "abc", Value: "cde"}, {Name: "123", Value: "432"}}
 	ps2 := newParams(p2)
 	assert.Equal(t, "cde", ps2.ValueOf("abc"))
 	assert.Equal(t, "432", ps2.ValueOf("123"))
@@ -27,22 +30,177 @@ func TestParams_ValueOf(t *testing.T) {
 }
 
 func TestParams_Value(t *testing.T) {
-	var p []*tree.Pair
+	var p []tree.Pair
 	ps := newParams(p)
 	assert.Empty(t, ps.ValueOf("key"))
 
-	p2 := []*tree.Pair{&tree.Pair{"abc", "cde"}, &tree.Pair{"123", "432"}}
+	p2 := []tree.Pair{{Name: "abc", Value: "cde"}, {Name: "123", Value: "432"}}
 	ps2 := newParams(p2)
 	assert.Equal(t, "cde", ps2.Value(0))
 	assert.Equal(t, "432", ps2.Value(1))
 	assert.Equal(t, "", ps2.Value(3))
 }
 
+func TestParams_Name(t *testing.T) {
+	ps := newParams([]tree.Pair{{Name: "abc", Value: "cde"}, {Name: "123", Value: "432"}})
+	assert.Equal(t, "abc", ps.Name(0))
+	assert.Equal(t, "123", ps.Name(1))
+	assert.Equal(t, "", ps.Name(2))
+}
+
 func TestParams_Count(t *testing.T) {
-	var p []*tree.Pair
+	var p []tree.Pair
 	ps := newParams(p)
 	assert.Equal(t, 0, ps.Count())
-	p2 := []*tree.Pair{&tree.Pair{"abc", "cde"}, &tree.Pair{"123", "432"}}
+	p2 := []tree.Pair{{Name: "abc", Value: "cde"}, {Name: "123", Value: "432"}}
 	ps2 := newParams(p2)
 	assert.Equal(t, 2, ps2.Count())
 }
+
+func TestParams_Typed(t *testing.T) {
+	ps := newParams([]tree.Pair{
+		{Name: "id", Value: "42"},
+		{Name: "big", Value: "9223372036854775807"},
+		{Name: "flag", Value: "true"},
+		{Name: "uuid", Value: "123E4567-e89b-12d3-a456-426614174000"},
+		{Name: "bad", Value: "x-1"},
+	})
+
+	i, err := ps.Int("id")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, i)
+
+	i64, err := ps.Int64("big")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9223372036854775807), i64)
+
+	b, err := ps.Bool("flag")
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	u, err := ps.UUID("uuid")
+	assert.NoError(t, err)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", u.String())
+
+	_, err = ps.Int("missing")
+	assert.Equal(t, &ParamError{"missing", ErrParamNotFound}, err)
+	_, err = ps.Bool("missing")
+	assert.Equal(t, &ParamError{"missing", ErrParamNotFound}, err)
+	_, err = ps.UUID("missing")
+	assert.Equal(t, &ParamError{"missing", ErrParamNotFound}, err)
+
+	_, err = ps.Int("bad")
+	assert.Error(t, err)
+	assert.Equal(t, "bad", err.(*ParamError).Name)
+	assert.Equal(t, strconv.ErrSyntax, err.(*ParamError).Err.(*strconv.NumError).Err)
+	_, err = ps.Bool("bad")
+	assert.Error(t, err)
+	_, err = ps.UUID("bad")
+	assert.Error(t, err)
+}
+
+func TestRouter_TypedParams(t *testing.T) {
+	router := NewRouter()
+	var values []interface{}
+	var errs []error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values, errs = nil, nil
+		params := RequestParams(r)
+		for i := 0; i < params.Count(); i++ {
+			value, err := params.Typed(params.(*Params).pairs[i].Name)
+			values, errs = append(values, value), append(errs, err)
+		}
+	})
+	router.GET("/users/{id:int}/{slug:alpha}", handler)
+	router.GET("/items/{id:uuid}/{rest...}", handler)
+	router.Host("{tenant:int}.example.com", func(rr RouteRegistrar) {
+		rr.GET("/orders/{order:[0-9]+}", handler)
+	})
+	router.Mount("/sub/{n:int}", router)
+
+	serve := func(url string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	serve("/users/-42/bob")
+	assert.Equal(t, []interface{}{-42, "bob"}, values)
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	serve("/items/123e4567-e89b-12d3-a456-426614174000/a/b")
+	u, _ := ParseUUID("123e4567-e89b-12d3-a456-426614174000")
+	assert.Equal(t, []interface{}{u, "a/b"}, values)
+
+	serve("http://7.example.com/orders/0012")
+	assert.Equal(t, []interface{}{7, "0012"}, values)
+
+	serve("/sub/3/users/5/al")
+	assert.Equal(t, []interface{}{3, 5, "al"}, values)
+
+	serve("/users/99999999999999999999/bob")
+	assert.Nil(t, values[0])
+	assert.Error(t, errs[0])
+	assert.IsType(t, (*ParamError)(nil), errs[0])
+
+	_, err := newParams(nil).Typed("id")
+	assert.Equal(t, ErrParamNotFound, err.(*ParamError).Err)
+	value, err := newParams([]tree.Pair{{Name: "id", Value: "1"}}).Typed("id")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+}
+
+func TestParseUUID(t *testing.T) {
+	for _, s := range []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", "123e4567-e89b-12d3-a456_426614174000"} {
+		_, err := ParseUUID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+type otherCtxKey string
+
+func TestRequestParams(t *testing.T) {
+	var params ParamsHolder
+	var other interface{}
+	r := NewRouter()
+	r.GET("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params = RequestParams(req)
+		other = req.Context().Value(otherCtxKey("RequestParams"))
+		id, err := params.Int("id")
+		assert.NoError(t, err)
+		assert.Equal(t, 7, id)
+	}))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/7", nil))
+	assert.NotNil(t, params)
+	assert.Nil(t, other)
+
+	assert.Equal(t, 0, RequestParams(httptest.NewRequest("GET", "/", nil)).Count())
+}
+
+func TestRouter_ServeHTTPParamsAllocations(t *testing.T) {
+	r := newBenchRouter()
+	w := &nopResponseWriter{http.Header{}}
+	req := httptest.NewRequest("GET", "/static/css/app.css", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		r.ServeHTTP(w, req)
+	})
+	//context of params and the shallow copy of request made by WithContext, the context is not pooled
+	//as handlers may keep it after they return
+	assert.Equal(t, float64(2), allocs)
+}
+
+func TestRouter_RequestParamsShouldStayValidAfterHandlerReturns(t *testing.T) {
+	var kept []*http.Request
+	r := NewRouter()
+	r.GET("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		kept = append(kept, req)
+	}))
+	r.GET("/posts/{a}/{b}/{c}/{d}/{e}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		kept = append(kept, req)
+	}))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/2", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/posts/1/2/3/4/5", nil))
+	assert.Equal(t, "1", RequestParams(kept[0]).ValueOf("id"))
+	assert.Equal(t, "2", RequestParams(kept[1]).ValueOf("id"))
+	assert.Equal(t, "5", RequestParams(kept[2]).ValueOf("e"))
+	assert.Equal(t, "/users/{id}", CurrentRoute(kept[0]).Pattern)
+	assert.NoError(t, kept[0].Context().Err())
+}
@@ -0,0 +1,175 @@
+package mux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder records status code, body size and state of response written through it, e.g. for metrics,
+// tracing and access logs. Handlers are served with the http.ResponseWriter returned by NewResponseRecorder or Reset,
+// which is http.Flusher, http.Hijacker and io.ReaderFrom only if the wrapped writer is
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status   int
+	size     int64
+	hijacked bool
+}
+
+// NewResponseRecorder wraps w, handler has to be served with the returned writer for its response to be recorded
+func NewResponseRecorder(w http.ResponseWriter) (*ResponseRecorder, http.ResponseWriter) {
+	r := &ResponseRecorder{}
+	return r, r.Reset(w)
+}
+
+// Reset clears recorded response and wraps w, it returns writer to be passed to handler like NewResponseRecorder,
+// the writer does not allocate, so that recorders can be pooled
+func (r *ResponseRecorder) Reset(w http.ResponseWriter) http.ResponseWriter {
+	*r = ResponseRecorder{ResponseWriter: w}
+	_, flusher := w.(http.Flusher)
+	_, hijacker := w.(http.Hijacker)
+	_, readerFrom := w.(io.ReaderFrom)
+	switch {
+	case flusher && hijacker && readerFrom:
+		return recorderFlusherHijackerReaderFrom{r}
+	case flusher && hijacker:
+		return recorderFlusherHijacker{r}
+	case flusher && readerFrom:
+		return recorderFlusherReaderFrom{r}
+	case hijacker && readerFrom:
+		return recorderHijackerReaderFrom{r}
+	case flusher:
+		return recorderFlusher{r}
+	case hijacker:
+		return recorderHijacker{r}
+	case readerFrom:
+		return recorderReaderFrom{r}
+	}
+	return r
+}
+
+func (r *ResponseRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 && (statusCode >= 200 || statusCode == http.StatusSwitchingProtocols) { //informational headers may be followed by others
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	r.writeHeaderOK()
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns status code of response, 0 if header is not written yet
+func (r *ResponseRecorder) Status() int {
+	return r.status
+}
+
+// Size returns size of body written
+func (r *ResponseRecorder) Size() int64 {
+	return r.size
+}
+
+// Hijacked reports whether connection is hijacked
+func (r *ResponseRecorder) Hijacked() bool {
+	return r.hijacked
+}
+
+// Committed reports whether header is written or connection is hijacked, a response can not be sent then
+func (r *ResponseRecorder) Committed() bool {
+	return r.status != 0 || r.hijacked
+}
+
+// writeHeaderOK records the implicit 200 of body written before header
+func (r *ResponseRecorder) writeHeaderOK() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+}
+
+func (r *ResponseRecorder) flush() {
+	r.writeHeaderOK()
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r *ResponseRecorder) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (r *ResponseRecorder) readFrom(src io.Reader) (int64, error) {
+	r.writeHeaderOK()
+	n, err := r.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.size += n
+	return n, err
+}
+
+// Writers returned by Reset, one for each combination of optional interfaces of wrapped writer,
+// they hold the recorder only, so that they are stored in http.ResponseWriter without allocation
+type (
+	recorderFlusher                   struct{ *ResponseRecorder }
+	recorderHijacker                  struct{ *ResponseRecorder }
+	recorderReaderFrom                struct{ *ResponseRecorder }
+	recorderFlusherHijacker           struct{ *ResponseRecorder }
+	recorderFlusherReaderFrom         struct{ *ResponseRecorder }
+	recorderHijackerReaderFrom        struct{ *ResponseRecorder }
+	recorderFlusherHijackerReaderFrom struct{ *ResponseRecorder }
+)
+
+func (w recorderFlusher) Flush() {
+	w.flush()
+}
+
+func (w recorderHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w recorderReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+func (w recorderFlusherHijacker) Flush() {
+	w.flush()
+}
+
+func (w recorderFlusherHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w recorderFlusherReaderFrom) Flush() {
+	w.flush()
+}
+
+func (w recorderFlusherReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+func (w recorderHijackerReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w recorderHijackerReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+func (w recorderFlusherHijackerReaderFrom) Flush() {
+	w.flush()
+}
+
+func (w recorderFlusherHijackerReaderFrom) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w recorderFlusherHijackerReaderFrom) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
@@ -0,0 +1,108 @@
+package mux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flushWriter struct {
+	nopResponseWriter
+	flushed bool
+}
+
+func (w *flushWriter) Flush() {
+	w.flushed = true
+}
+
+type hijackReadFromWriter struct {
+	nopResponseWriter
+	read int64
+}
+
+func (w *hijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func (w *hijackReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	n, err := io.Copy(io.Discard, src)
+	w.read += n
+	return n, err
+}
+
+func TestResponseRecorder(t *testing.T) {
+	recorder, w := NewResponseRecorder(&nopResponseWriter{http.Header{}})
+	w.WriteHeader(http.StatusEarlyHints)
+	assert.Equal(t, 0, recorder.Status())
+	assert.False(t, recorder.Committed())
+	w.WriteHeader(http.StatusSwitchingProtocols)
+	assert.Equal(t, http.StatusSwitchingProtocols, recorder.Status())
+
+	recorder, w = NewResponseRecorder(httptest.NewRecorder())
+	w.Write([]byte("abc"))
+	w.WriteHeader(http.StatusCreated)
+	assert.Equal(t, http.StatusOK, recorder.Status())
+	assert.Equal(t, int64(3), recorder.Size())
+	assert.True(t, recorder.Committed())
+	assert.NoError(t, http.NewResponseController(w).Flush())
+	assert.True(t, recorder.ResponseWriter.(*httptest.ResponseRecorder).Flushed)
+
+	recorder.Reset(httptest.NewRecorder())
+	assert.Equal(t, 0, recorder.Status())
+	assert.Equal(t, int64(0), recorder.Size())
+}
+
+func TestResponseRecorder_ShouldExposeInterfacesOfWrappedWriter(t *testing.T) {
+	_, w := NewResponseRecorder(&nopResponseWriter{http.Header{}})
+	assert.Implements(t, (*http.ResponseWriter)(nil), w)
+	_, ok := w.(http.Flusher)
+	assert.False(t, ok)
+	_, ok = w.(http.Hijacker)
+	assert.False(t, ok)
+	_, ok = w.(io.ReaderFrom)
+	assert.False(t, ok)
+
+	flusher := &flushWriter{nopResponseWriter: nopResponseWriter{http.Header{}}}
+	recorder, w := NewResponseRecorder(flusher)
+	_, ok = w.(http.Hijacker)
+	assert.False(t, ok)
+	w.(http.Flusher).Flush()
+	assert.True(t, flusher.flushed)
+	assert.Equal(t, http.StatusOK, recorder.Status())
+
+	inner := &hijackReadFromWriter{nopResponseWriter: nopResponseWriter{http.Header{}}}
+	recorder, w = NewResponseRecorder(inner)
+	_, ok = w.(http.Flusher)
+	assert.False(t, ok)
+	n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("body"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.Equal(t, int64(4), inner.read)
+	assert.Equal(t, int64(4), recorder.Size())
+	_, _, err = w.(http.Hijacker).Hijack()
+	assert.NoError(t, err)
+	assert.True(t, recorder.Hijacked())
+	assert.Equal(t, inner, http.ResponseWriter(recorder.Unwrap()))
+}
+
+func TestResponseRecorder_ResetShouldNotAllocate(t *testing.T) {
+	var recorder ResponseRecorder
+	inner := &hijackReadFromWriter{nopResponseWriter: nopResponseWriter{http.Header{}}}
+	allocs := testing.AllocsPerRun(100, func() {
+		w := recorder.Reset(inner)
+		w.WriteHeader(http.StatusOK)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestNormalizeMethod(t *testing.T) {
+	assert.Equal(t, "PATCH", NormalizeMethod("PATCH", "OTHER"))
+	assert.Equal(t, "OTHER", NormalizeMethod("PURGE", "OTHER"))
+	assert.Equal(t, "OTHER", NormalizeMethod("get", "OTHER"))
+}
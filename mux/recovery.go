@@ -0,0 +1,59 @@
+package mux
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicInfo describes a panic recovered by Router
+type PanicInfo struct {
+	//Value is the recovered value
+	Value interface{}
+
+	//Stack is the stack trace of panicking goroutine
+	Stack []byte
+
+	//Route is the route matched by request, nil if no route is matched
+	Route *MatchedRoute
+
+	//Request is the request being served, with params of matched route, its context is made for the request
+	//and never reused, so that Request and its params stay valid once PanicReporter returns
+	Request *http.Request
+
+	//Committed reports whether response header was written or connection was hijacked before panic,
+	//a response can not be sent then
+	Committed bool
+}
+
+// PanicReporter is called with panics recovered by Router, e.g. to log them
+type PanicReporter func(info *PanicInfo)
+
+// recovering reports whether panics of handlers are recovered
+func (r *Router) recovering() bool {
+	return r.PanicFunc != nil || r.RecoverPanics || r.PanicReporter != nil
+}
+
+// handlePanic handles panic recovered from handler serving req, it is reported to PanicReporter and response is sent
+// by PanicFunc or as 500 if it is not committed yet, otherwise connection is aborted.
+// http.ErrAbortHandler is passed on to http.Server as it is meant to abort connection
+func (r *Router) handlePanic(rev interface{}, w *ResponseRecorder, req *http.Request, matched *MatchedRoute) {
+	if rev == http.ErrAbortHandler {
+		panic(rev)
+	}
+	if r.PanicReporter != nil {
+		info := &PanicInfo{Value: rev, Stack: debug.Stack(), Request: req, Committed: w.Committed()}
+		if matched.Node != nil {
+			route := *matched
+			info.Route = &route
+		}
+		r.PanicReporter(info)
+	}
+	if w.Committed() {
+		panic(http.ErrAbortHandler)
+	}
+	if r.PanicFunc != nil {
+		r.PanicFunc(rev)(w.ResponseWriter, req)
+	} else {
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
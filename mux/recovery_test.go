@@ -0,0 +1,117 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_RecoverPanics(t *testing.T) {
+	var infos []PanicInfo
+	var id string
+	r := NewRouter()
+	r.RecoverPanics = true
+	r.PanicReporter = func(info *PanicInfo) {
+		id = RequestParams(info.Request).ValueOf("id")
+		infos = append(infos, *info)
+	}
+	r.GET("/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})).Name("user")
+	r.GET("/committed", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	}))
+	r.GET("/abort", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/7", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "boom", infos[0].Value)
+	assert.Contains(t, string(infos[0].Stack), "TestRouter_RecoverPanics")
+	assert.Equal(t, "/users/{id}", infos[0].Route.Pattern)
+	assert.Equal(t, "user", infos[0].Route.Name)
+	assert.Equal(t, "7", id)
+	assert.False(t, infos[0].Committed)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/about", nil))
+	assert.Equal(t, "7", RequestParams(infos[0].Request).ValueOf("id")) //request is not recycled
+
+	w = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/committed", nil))
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+	assert.Len(t, infos, 2)
+	assert.True(t, infos[1].Committed)
+	assert.Equal(t, "/committed", infos[1].Route.Pattern)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	})
+	assert.Len(t, infos, 2)
+}
+
+func TestRouter_PanicFuncShouldNotWriteCommittedResponse(t *testing.T) {
+	var recovered []interface{}
+	r := NewRouter()
+	r.PanicFunc = func(rev interface{}) http.HandlerFunc {
+		recovered = append(recovered, rev)
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	r.GET("/before", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Test", "1")
+		panic("before")
+	}))
+	r.GET("/after", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after")
+	}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/before", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, []interface{}{"before"}, recovered)
+
+	w = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/after", nil))
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []interface{}{"before"}, recovered)
+}
+
+func TestRouter_RecoverPanicsShouldKeepResponseWriterInterfaces(t *testing.T) {
+	r := NewRouter()
+	r.RecoverPanics = true
+	var hijackErr error
+	var isHijacker bool
+	r.GET("/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.NewResponseController(w).Flush()
+		_, isHijacker = w.(http.Hijacker)
+		_, _, hijackErr = http.NewResponseController(w).Hijack()
+	}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, w.Flushed)
+	assert.False(t, isHijacker) //httptest.ResponseRecorder is not http.Hijacker
+	assert.ErrorIs(t, hijackErr, http.ErrNotSupported)
+}
+
+func TestRouter_RecoverPanicsShouldNotAllocate(t *testing.T) {
+	r := newBenchRouter()
+	r.RecoverPanics = true
+	w := &nopResponseWriter{http.Header{}}
+	req := httptest.NewRequest("GET", "/users", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		r.ServeHTTP(w, req)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
@@ -1,31 +1,234 @@
 package mux
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
 
 type methodContext struct {
-	handler    http.Handler
-	handleFunc http.HandlerFunc
+	handler         http.Handler
+	handleFunc      http.HandlerFunc
+	group           *group
+	middlewareChain []MiddlewareFunc
+	name            string
+	//host and urlPatterns of variants of path are set once route is registered
+	host        string
+	urlPatterns []*urlPattern
+	router      *Router
+	table       *Table
+}
+
+func newMethodContext(handler http.Handler, group *group) *methodContext {
+	mc := &methodContext{handler: handler, group: group}
+	mc.compose()
+	return mc
 }
 
+// Use sets middleware of route, routes already registered are composed again on a copy of served table,
+// see Router.Use
 func (mc *methodContext) Use(middleware ...MiddlewareFunc) {
-	mc.handleFunc = mc.handler.ServeHTTP
+	mc.router.update(func() {
+		mc.middlewareChain = middleware
+		mc.compose()
+	})
+}
+
+func (mc *methodContext) Name(name string) RouteEntry {
+	mc.name = name
+	if mc.table != nil {
+		mc.router.mu.Lock()
+		defer mc.router.mu.Unlock()
+		if err := mc.table.addName(name, mc); err != nil {
+			panic(err)
+		}
+	}
+	return mc
+}
+
+// compose wraps handler with own middleware, then with middleware of each group from inner to outer
+func (mc *methodContext) compose() {
+	mc.handleFunc = mc.wrap(mc.handler.ServeHTTP)
+}
+
+// wrap wraps handleFunc with middleware of method and of its groups
+func (mc *methodContext) wrap(handleFunc http.HandlerFunc) http.HandlerFunc {
+	handleFunc = wrapMiddleware(handleFunc, mc.middlewareChain)
+	for g := mc.group; g != nil; g = g.parent {
+		handleFunc = wrapMiddleware(handleFunc, g.middlewareChain)
+	}
+	return handleFunc
+}
+
+// serveFunc additionally wraps handleFunc composed by wrap with global middleware and CORS headers of router
+func (mc *methodContext) serveFunc(router *Router, handleFunc http.HandlerFunc) http.HandlerFunc {
+	serveFunc := wrapMiddleware(handleFunc, router.middlewareChain)
+	if cors := mc.corsConfig(); cors != nil {
+		serveFunc = cors.wrap(serveFunc)
+	}
+	return serveFunc
+}
+
+func wrapMiddleware(handleFunc http.HandlerFunc, middleware []MiddlewareFunc) http.HandlerFunc {
 	for _, m := range middleware {
-		mc.handleFunc = m(mc.handleFunc).ServeHTTP
+		handleFunc = m(handleFunc).ServeHTTP
 	}
+	return handleFunc
+}
+
+const (
+	allowWithHEAD = 1 << iota
+	allowWithOPTIONS
+)
+
+// routeHandler serves a method of route, it is composed with middleware of router for the table of route
+type routeHandler struct {
+	methodCtx *methodContext
+	serveFunc http.HandlerFunc
+}
+
+type Route struct {
+	methods map[string]*methodContext
+	//handlers serve methods of route, they are composed again for a copy of table rather than modified
+	handlers     map[string]routeHandler
+	mountHandler routeHandler
+	//allowed methods and Allow header values, indexed by allowWithHEAD and allowWithOPTIONS flags
+	allowMethods [4][]string
+	allow        [4]string
+	mount        *methodContext
+	//mountSegments are segments of mount prefix in pattern of route, variants of prefix differ in segments
+	mountSegments  int
+	optionsFunc    http.HandlerFunc
+	notAllowedFunc http.HandlerFunc
+	//converters convert host params and path params, resolved once route is added, nil for params without converter
+	converters []tree.ConvertFunc
+	node       tree.NodeInterface
+	host       string
+	pattern    string
 }
 
-type Route map[string]*methodContext
+func newRoute() *Route {
+	return &Route{methods: make(map[string]*methodContext)}
+}
+
+// clone copies route for a copy of table, its methods can be changed without touching route
+func (r *Route) clone() *Route {
+	route := *r
+	route.methods = make(map[string]*methodContext, len(r.methods))
+	for method, methodCtx := range r.methods {
+		route.methods[method] = methodCtx
+	}
+	return &route
+}
 
-func (r Route) Methods() (methods []string) {
-	for key := range r {
+// Methods returns registered methods of route in alphabetical order
+func (r *Route) Methods() (methods []string) {
+	for key := range r.methods {
 		methods = append(methods, key)
 	}
+	sort.Strings(methods)
 	return
 }
 
-func (r Route) MethodHandleFunc(method string) (handleFunc http.HandlerFunc) {
-	if ctx, ok := r[method]; ok {
+// pattern returns host and path pattern of route, the variant with all optional parts stands for the route
+func (mc *methodContext) pattern() string {
+	return mc.host + mc.urlPatterns[0].pattern
+}
+
+// namesake returns a method context named as mc which is left in routes of variants of mc in t, nil if none is left
+func (mc *methodContext) namesake(t *Table) *methodContext {
+	for _, variant := range mc.urlPatterns {
+		route := t.route(mc.host, variant.pattern)
+		if route == nil {
+			continue
+		}
+		if route.mount != nil && route.mount.name == mc.name {
+			return route.mount
+		}
+		for _, methodCtx := range route.methods {
+			if methodCtx.name == mc.name {
+				return methodCtx
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Route) MethodHandleFunc(method string) (handleFunc http.HandlerFunc) {
+	if ctx, ok := r.methods[method]; ok {
 		handleFunc = ctx.handleFunc
 	}
 	return handleFunc
 }
+
+// compose prepares handlers of route with global middleware of router, so that nothing is built per request
+func (r *Route) compose(router *Router) {
+	r.handlers = make(map[string]routeHandler, len(r.methods))
+	for method, methodCtx := range r.methods {
+		methodCtx.compose()
+		r.handlers[method] = routeHandler{methodCtx, methodCtx.serveFunc(router, methodCtx.handleFunc)}
+	}
+	r.mountHandler = routeHandler{}
+	if r.mount != nil {
+		r.mount.compose()
+		handleFunc := r.mount.handleFunc
+		if mount, ok := r.mount.handler.(*mountHandler); ok { //prefix is stripped by segments of this variant
+			handleFunc = r.mount.wrap((&mountHandler{handler: mount.handler, segments: r.mountSegments, router: router}).ServeHTTP)
+		}
+		r.mountHandler = routeHandler{r.mount, r.mount.serveFunc(router, handleFunc)}
+	}
+	for flags := range r.allow {
+		methods := r.Methods()
+		if _, ok := r.methods["GET"]; ok && flags&allowWithHEAD != 0 {
+			methods = uniqueAppend(methods, "HEAD")
+		}
+		if flags&allowWithOPTIONS != 0 {
+			methods = uniqueAppend(methods, "OPTIONS")
+		}
+		sort.Strings(methods)
+		r.allowMethods[flags] = methods
+		r.allow[flags] = strings.Join(methods, ", ")
+	}
+	optionsFunc := wrapMiddleware(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Allow", r.allow[allowFlags(router, true)])
+		w.WriteHeader(200)
+	}, router.middlewareChain)
+	r.optionsFunc = func(w http.ResponseWriter, req *http.Request) {
+		if cors := r.preflightConfig(router, req); cors != nil {
+			cors.preflight(w.Header(), req, r.allow[allowFlags(router, true)])
+		}
+		optionsFunc(w, req)
+	}
+	r.notAllowedFunc = wrapMiddleware(func(w http.ResponseWriter, req *http.Request) {
+		flags := allowFlags(router, router.HandleOPTIONS)
+		w.Header().Set("Allow", r.allow[flags])
+		if router.MethodNotAllowedHandler != nil {
+			req = req.WithContext(context.WithValue(req.Context(), allowedMethodsCtxKey{}, r.allowMethods[flags]))
+			router.MethodNotAllowedHandler.ServeHTTP(w, req)
+		} else {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	}, router.middlewareChain)
+}
+
+// allowFlags returns index of allowed methods, HEAD is included for GET routes if router handles HEAD
+func allowFlags(router *Router, withOPTIONS bool) (flags int) {
+	if router.HandleHEAD {
+		flags |= allowWithHEAD
+	}
+	if withOPTIONS {
+		flags |= allowWithOPTIONS
+	}
+	return
+}
+
+type allowedMethodsCtxKey struct{}
+
+// AllowedMethods returns methods in Allow header of response to request passed to MethodNotAllowedHandler
+func AllowedMethods(r *http.Request) []string {
+	methods, _ := r.Context().Value(allowedMethodsCtxKey{}).([]string)
+	return append([]string(nil), methods...)
+}
@@ -2,17 +2,26 @@ package mux
 
 import "net/http"
 
+type groupRoute struct {
+	host      string
+	path      string
+	method    string
+	methodCtx *methodContext
+}
+
 type group struct {
 	parent          *group
 	subGroups       []*group
 	path            string
+	host            string
 	methodCxtRefs   []*methodContext
-	routes          map[string]map[string]*methodContext
+	routes          []groupRoute
 	middlewareChain []MiddlewareFunc
+	cors            *CORSConfig
 }
 
 func newGroup(path string) *group {
-	return &group{path: path, routes: make(map[string]map[string]*methodContext)}
+	return &group{path: path}
 }
 
 func (g *group) root() (group *group) {
@@ -23,63 +32,99 @@ func (g *group) root() (group *group) {
 	return
 }
 
-func (g *group) getRoutes() (routes map[string]map[string]*methodContext) {
-	routes = make(map[string]map[string]*methodContext)
-	for p, mctx := range g.routes {
-		routes[g.path+p] = mctx
+// getRoutes returns routes of group and its sub groups with full path and bound host
+func (g *group) getRoutes() (routes []groupRoute) {
+	for _, route := range g.routes {
+		route.path = g.path + route.path
+		route.host = g.hostPattern()
+		routes = append(routes, route)
 	}
 	for _, subGroup := range g.subGroups {
-		subRoutes := subGroup.getRoutes()
-		for p, mctx := range subRoutes {
-			routes[g.path+p] = mctx
+		for _, route := range subGroup.getRoutes() {
+			route.path = g.path + route.path
+			routes = append(routes, route)
 		}
 	}
 	return
 }
 
+// hostPattern returns host bound to group or its nearest parent
+func (g *group) hostPattern() string {
+	for group := g; group != nil; group = group.parent {
+		if group.host != "" {
+			return group.host
+		}
+	}
+	return ""
+}
+
+// Use sets middleware of routes in group and its sub groups, see Router.Use
 func (g *group) Use(middleware ...MiddlewareFunc) {
-	g.middlewareChain = middleware
+	g.router().update(func() {
+		g.middlewareChain = middleware
+		g.composeRoutes()
+	})
 }
 
-func (g *group) Handle(path string, handleFunc http.Handler, httpMethod ...string) MiddlewareRegistrar {
-	methodCtx := &methodContext{handleFunc, handleFunc.ServeHTTP}
-	mctx, ok := g.routes[path]
-	if !ok {
-		mctx = make(map[string]*methodContext)
-		g.routes[path] = mctx
+// composeRoutes rebuilds handlers of routes in group and its sub groups
+func (g *group) composeRoutes() {
+	for _, methodCtx := range g.methodCxtRefs {
+		methodCtx.compose()
+	}
+	for _, subGroup := range g.subGroups {
+		subGroup.composeRoutes()
+	}
+}
+
+// router returns router routes of group are registered to, nil if they are not registered yet
+func (g *group) router() *Router {
+	for _, methodCtx := range g.methodCxtRefs {
+		if methodCtx.router != nil {
+			return methodCtx.router
+		}
 	}
+	for _, subGroup := range g.subGroups {
+		if router := subGroup.router(); router != nil {
+			return router
+		}
+	}
+	return nil
+}
+
+func (g *group) Handle(path string, handleFunc http.Handler, httpMethod ...string) RouteEntry {
+	methodCtx := newMethodContext(handleFunc, g)
 	for _, m := range httpMethod {
-		mctx[m] = methodCtx
+		g.routes = append(g.routes, groupRoute{path: path, method: m, methodCtx: methodCtx})
 	}
 	g.methodCxtRefs = append(g.methodCxtRefs, methodCtx)
 	return methodCtx
 }
 
-func (g *group) GET(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) GET(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "GET")
 }
 
-func (g *group) POST(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) POST(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "POST")
 }
 
-func (g *group) PUT(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) PUT(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "PUT")
 }
 
-func (g *group) DELETE(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) DELETE(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "DELETE")
 }
 
-func (g *group) OPTIONS(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) OPTIONS(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "OPTIONS")
 }
 
-func (g *group) HEAD(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) HEAD(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "HEAD")
 }
 
-func (g *group) PATCH(path string, handleFunc http.Handler) MiddlewareRegistrar {
+func (g *group) PATCH(path string, handleFunc http.Handler) RouteEntry {
 	return g.Handle(path, handleFunc, "PATCH")
 }
 
@@ -90,3 +135,12 @@ func (g *group) Group(path string, groupFunc func(routeRegistrar RouteRegistrar)
 	g.subGroups = append(g.subGroups, subGroup)
 	return subGroup
 }
+
+func (g *group) Host(host string, groupFunc func(routeRegistrar RouteRegistrar)) MiddlewareRegistrar {
+	subGroup := newGroup("")
+	subGroup.host = host
+	subGroup.parent = g
+	groupFunc(subGroup)
+	g.subGroups = append(g.subGroups, subGroup)
+	return subGroup
+}
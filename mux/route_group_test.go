@@ -22,6 +22,39 @@ func TestGroup_Group(t *testing.T) {
 	assert.Equal(t, g, sg.(*group).root())
 }
 
+func tagMiddleware(tag string, trace *[]string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			*trace = append(*trace, tag)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
 func TestGroup_Use(t *testing.T) {
-	assert.True(t, true)
+	var trace []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace = append(trace, "handler")
+	})
+	g := newGroup("/outer")
+	before := g.GET("/before", handler)
+	g.Use(tagMiddleware("outer", &trace))
+	after := g.GET("/after", handler)
+	g.Group("/inner", func(routeRegistrar RouteRegistrar) {
+		routeRegistrar.Use(tagMiddleware("inner", &trace))
+		routeRegistrar.GET("/route", handler).Use(tagMiddleware("route", &trace))
+	})
+
+	before.(*methodContext).handleFunc(nil, nil)
+	assert.Equal(t, []string{"outer", "handler"}, trace)
+
+	trace = nil
+	after.(*methodContext).handleFunc(nil, nil)
+	assert.Equal(t, []string{"outer", "handler"}, trace)
+
+	trace = nil
+	routes := g.getRoutes()
+	assert.Equal(t, "/outer/inner/route", routes[2].path)
+	routes[2].methodCtx.handleFunc(nil, nil)
+	assert.Equal(t, []string{"outer", "inner", "route", "handler"}, trace)
 }
@@ -1,163 +1,680 @@
 package mux
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	pathpkg "path"
 	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/mfantcy/rdx-router/tree"
-	"strings"
 )
 
+var (
+	ErrDuplicateRoute = errors.New("method is already registered")
+	ErrInvalidMethod  = errors.New("invalid http method")
+	ErrDuplicateName  = errors.New("route name is already used")
+)
+
+var methodRegexp = regexp.MustCompile("^[A-Z]+(-[A-Z]+)*$")
+
+var standardMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true,
+}
+
+// NormalizeMethod returns method if it is defined by RFC 7231 or RFC 5789, other otherwise,
+// e.g. for labels of metrics and names of spans, which must not be chosen by clients
+func NormalizeMethod(method, other string) string {
+	if standardMethods[method] {
+		return method
+	}
+	return other
+}
+
+// RouteError reports a route which can not be registered,
+// Err is ErrDuplicateRoute, ErrInvalidMethod, ErrDuplicateName or one of the Err* values of tree
+type RouteError struct {
+	Method  string
+	Host    string
+	Pattern string
+	//Variant is the variant of Pattern with optional parts expanded the error is found in, empty if it is Pattern
+	Variant string
+	Err     error
+	Detail  string
+}
+
+func (e *RouteError) Error() string {
+	msg := "route '"
+	if e.Method != "" {
+		msg += e.Method + " "
+	}
+	msg += e.Host + e.Pattern + "'"
+	if e.Variant != "" {
+		msg += " (variant '" + e.Variant + "')"
+	}
+	msg += ": " + e.Err.Error()
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
 type PanicHandleFunc func(recovered interface{}) http.HandlerFunc
 
 type Router struct {
+	//FixTrailingSlash serves path differing in trailing slash from registered one by its route
 	FixTrailingSlash bool
 
+	//RedirectTrailingSlash redirects path differing in trailing slash from registered one to it,
+	//it takes precedence over FixTrailingSlash
+	RedirectTrailingSlash bool
+
+	//RedirectFixedPath redirects path differing from registered one in case, in duplicate slashes or in "." and ".."
+	//segments to it
+	RedirectFixedPath bool
+
+	//CaseInsensitive serves path differing from registered one in case by its route, params are kept as they are,
+	//paths are redirected instead if RedirectFixedPath
+	CaseInsensitive bool
+
+	//UseRawPath routes requests by escaped path, so that params may contain encoded "/", values of params are
+	//unescaped one by one, static parts of patterns have to be written escaped, regexps and matchers see escaped values
+	UseRawPath bool
+
+	//Strict makes registration fail on methods registered twice for a path and on ambiguous patterns,
+	//instead of overwriting the previous handler, placeholders shadowed by others are ambiguous, see tree.TryAddThen
+	Strict bool
+
 	HandleMethodNotAllowed bool
 
 	HandleOPTIONS bool
 
+	//HandleHEAD serves HEAD requests of routes without HEAD handler by GET handler, body is discarded
+	HandleHEAD bool
+
 	NotFoundHandler http.HandlerFunc
 
 	MethodNotAllowedHandler http.HandlerFunc
 
+	//PanicFunc handles panics of handlers, it is not called if response is already committed,
+	//the connection is aborted instead
 	PanicFunc PanicHandleFunc
 
-	tree tree.TrieInterface
+	//RecoverPanics recovers panics of handlers without PanicFunc, 500 is responded unless response is committed
+	RecoverPanics bool
+
+	//PanicReporter is called with panics recovered by router before they are handled, e.g. to log them with stack,
+	//panics are recovered if it is set
+	PanicReporter PanicReporter
+
+	//table is the served *Table
+	table atomic.Value
+
+	//mu serializes registering, compiling and swapping of tables, it is never taken by ServeHTTP
+	mu sync.Mutex
+
+	//generation is increased whenever routes have to be composed again
+	generation int
 
 	middlewareChain []MiddlewareFunc
+	//routeAware is set by UseRouteAware, matched routes without params are attached to requests then
+	routeAware bool
+
+	cors *CORSConfig
 }
 
+// Use sets global middleware wrapping handlers of all routes, not found and method not allowed responses included.
+// Middleware is meant to be set up before serving. Handlers are composed on a copy of the served table which is
+// swapped in then, so that Use does not race with requests in flight, which are finished with previous middleware
 func (r *Router) Use(middleware ...MiddlewareFunc) {
-	r.middlewareChain = middleware
+	r.update(func() {
+		r.middlewareChain, r.routeAware = middleware, false
+	})
+}
+
+// UseRouteAware sets global middleware like Use, for middleware reading CurrentRoute, e.g. of metrics, tracing or
+// access logs. Matched routes without params are attached to their requests then, which costs 2 allocations for each
+// of their requests, routes without params are served without allocation otherwise
+func (r *Router) UseRouteAware(middleware ...MiddlewareFunc) {
+	r.update(func() {
+		r.middlewareChain, r.routeAware = middleware, true
+	})
+}
+
+// update applies change of middleware or CORS config to router, then composes routes with it on a copy of
+// served table which is swapped in, change is applied alone if r is nil, e.g. for routes not registered yet
+func (r *Router) update(change func()) {
+	if r == nil {
+		change()
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change()
+	r.generation++
+	t := r.current().clone()
+	r.composeTable(t)
+	r.table.Store(t)
+}
+
+// composeTable builds handlers of all routes of t with global middleware, t must not be served yet
+func (r *Router) composeTable(t *Table) {
+	t.notFoundFunc = wrapMiddleware(r.serveNotFound, r.middlewareChain)
+	t.redirectFunc = wrapMiddleware(r.serveRedirect, r.middlewareChain)
+	t.routeAware = r.routeAware
+	t.walk(func(node tree.NodeInterface) error {
+		if route, ok := node.Context().(*Route); ok {
+			route.compose(r)
+		}
+		return nil
+	})
+	t.generation = r.generation
+}
+
+// current returns the served table
+func (r *Router) current() *Table {
+	return r.table.Load().(*Table)
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if r.PanicFunc != nil {
-		defer r.recover(w, req)
-	}
-	var handleFunc http.HandlerFunc
-	if rt, p, ok := r.tree.Lookup(req.URL.Path, r.FixTrailingSlash); ok && rt != nil { //resource found
-		route := rt.(Route)
-		handleFunc = route.MethodHandleFunc(req.Method)
-		if len(p) > 0 {
-			req = toWithRequestParams(req, newParams(p))
-		}
-		if handleFunc == nil {
-			if req.Method == "OPTIONS" && r.HandleOPTIONS {
-				handleFunc = func(w http.ResponseWriter, req *http.Request) {
-					allowedMethods := route.Methods()
-					allowedMethods = uniqueAppend(allowedMethods, "OPTIONS")
-					w.Header().Set("Allow", strings.Join(allowedMethods, " "))
-					w.WriteHeader(200)
-				}
-			} else if r.MethodNotAllowedHandler != nil || r.HandleMethodNotAllowed { //method not allowed
-				handleFunc = func(w http.ResponseWriter, req *http.Request) {
-					allowedMethods := route.Methods()
-					if r.HandleOPTIONS {
-						allowedMethods = uniqueAppend(allowedMethods, "OPTIONS")
-					}
-					w.Header().Set("Allow", strings.Join(allowedMethods, " "))
-					if r.MethodNotAllowedHandler != nil {
-						r.MethodNotAllowedHandler.ServeHTTP(w, req)
-					} else {
-						http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-					}
-				}
+	buf := acquireRequestBuffer()
+	defer releaseRequestBuffer(buf)
+	pairs, outerConverters := buf.pairs, []tree.ConvertFunc(nil)
+	if outer, ok := req.Context().Value(mountParamsCtxKey{}).(*Params); ok { //mounted, params of prefix come first
+		pairs = append(pairs, outer.pairs...)
+		outerConverters = outer.converters
+	}
+	matched := &buf.matched
+	if r.recovering() {
+		w = buf.writer.Reset(w) //exposes interfaces of w only, without allocation
+		defer func() {
+			if rev := recover(); rev != nil {
+				r.handlePanic(rev, &buf.writer, req, matched) //req carries params once route is matched
 			}
+		}()
+	}
+	t := r.current()
+	path := req.URL.Path
+	if r.UseRawPath {
+		path = req.URL.EscapedPath()
+	}
+	rt, p, ok := t.lookup(req, path, r.FixTrailingSlash && !r.RedirectTrailingSlash, pairs)
+	if !ok && (r.RedirectTrailingSlash || r.RedirectFixedPath || r.CaseInsensitive) && strings.HasPrefix(path, "/") { //e.g. "OPTIONS *"
+		if fixed, redirect, found := r.fixPath(t, req, path); redirect {
+			t.redirectFunc(w, req.WithContext(context.WithValue(req.Context(), redirectCtxKey{}, fixed)))
+			return
+		} else if found {
+			rt, p, ok = t.lookup(req, fixed, false, pairs)
 		}
 	}
-
-	//not found
-	if handleFunc == nil {
-		handleFunc = func(w http.ResponseWriter, req *http.Request) {
-			if r.NotFoundHandler != nil {
-				r.NotFoundHandler.ServeHTTP(w, req)
-			} else {
-				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	if cap(p) > cap(buf.pairs) {
+		buf.pairs = p[:0] //keep grown buffer
+	}
+	if ok && rt != nil { //resource found
+		route := rt.(*Route)
+		if r.UseRawPath {
+			unescapeParams(p[len(pairs):])
+		}
+		matched.match(route)
+		if len(p) > 0 || t.routeAware {
+			converters := route.converters
+			if len(outerConverters) > 0 {
+				converters = append(append([]tree.ConvertFunc(nil), outerConverters...), route.converters...)
+			}
+			paramsCtx := newParamsContext(req.Context(), p, converters, matched)
+			matched = &paramsCtx.matched
+			req = toWithRequestParams(req, paramsCtx)
+		}
+		if handler, ok := route.handlers[req.Method]; ok {
+			matched.serveBy(handler.methodCtx, req.Method)
+			handler.serveFunc(w, req)
+			return
+		}
+		if handler := route.mountHandler; handler.methodCtx != nil {
+			matched.serveBy(handler.methodCtx, mountMethod)
+			handler.serveFunc(w, req)
+			return
+		}
+		if req.Method == "HEAD" && r.HandleHEAD {
+			if handler, ok := route.handlers["GET"]; ok {
+				matched.serveBy(handler.methodCtx, "GET")
+				serveHEAD(handler.serveFunc, w, req)
+				return
 			}
 		}
+		if req.Method == "OPTIONS" && r.HandleOPTIONS {
+			route.optionsFunc(w, req)
+			return
+		} else if r.MethodNotAllowedHandler != nil || r.HandleMethodNotAllowed { //method not allowed
+			route.notAllowedFunc(w, req)
+			return
+		}
+	}
+	//not found
+	t.notFoundFunc(w, req)
+}
 
+// fixPath finds registered path of request differing in trailing slash, case or cleanliness,
+// redirect reports whether request has to be redirected to it instead of being served
+func (r *Router) fixPath(t *Table, req *http.Request, path string) (fixed string, redirect bool, found bool) {
+	if r.RedirectTrailingSlash && len(path) > 1 {
+		fixed = path + "/"
+		if path[len(path)-1] == '/' {
+			fixed = path[:len(path)-1]
+		}
+		if _, _, ok := t.lookup(req, fixed, false, nil); ok {
+			return fixed, true, true
+		}
 	}
-	//global middleware
-	for _, middlewareFunc := range r.middlewareChain {
-		handleFunc = middlewareFunc(handleFunc).ServeHTTP
+	if r.RedirectFixedPath {
+		if fixed, ok := t.findCaseInsensitivePath(req, cleanPath(path), r.RedirectTrailingSlash); ok {
+			return fixed, true, true
+		}
+	}
+	if r.CaseInsensitive {
+		if fixed, ok := t.findCaseInsensitivePath(req, path, r.FixTrailingSlash && !r.RedirectTrailingSlash); ok {
+			return fixed, false, true
+		}
+	}
+	return "", false, false
+}
+
+// redirectCtxKey is the key of path fixed by ServeHTTP in context of request passed to serveRedirect
+type redirectCtxKey struct{}
+
+// serveRedirect redirects request to path fixed by ServeHTTP, it is composed with global middleware
+func (r *Router) serveRedirect(w http.ResponseWriter, req *http.Request) {
+	path, _ := req.Context().Value(redirectCtxKey{}).(string)
+	redirectTo(w, req, path, r.UseRawPath)
+}
+
+// redirectTo redirects request to path, prefix stripped by mounts of request is put back in front of path
+func redirectTo(w http.ResponseWriter, req *http.Request, path string, escaped bool) {
+	code := http.StatusPermanentRedirect
+	if req.Method == "GET" {
+		code = http.StatusMovedPermanently
+	}
+	if prefix, ok := req.Context().Value(mountPrefixCtxKey{}).(*mountPrefix); ok {
+		if escaped {
+			path = prefix.rawPath + path
+		} else {
+			path = prefix.path + path
+		}
+	}
+	location := &url.URL{Path: path, RawQuery: req.URL.RawQuery}
+	if escaped {
+		location.Path, _ = url.PathUnescape(path)
+		location.RawPath = path
+	}
+	http.Redirect(w, req, location.String(), code)
+}
+
+// unescapeParams unescapes values of params matched in escaped path, invalid values are kept as they are
+func unescapeParams(pairs []tree.Pair) {
+	for i := range pairs {
+		if strings.IndexByte(pairs[i].Value, '%') < 0 {
+			continue
+		}
+		if value, err := url.PathUnescape(pairs[i].Value); err == nil {
+			pairs[i].Value = value
+		}
+	}
+}
+
+// cleanPath removes duplicate slashes and resolves "." and ".." segments, trailing slash is kept
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := pathpkg.Clean("/" + p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func (r *Router) serveNotFound(w http.ResponseWriter, req *http.Request) {
+	if r.NotFoundHandler != nil {
+		r.NotFoundHandler.ServeHTTP(w, req)
+	} else {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 	}
-	handleFunc(w, req)
-	return
 }
 
 func NewRouter() *Router {
-	return &Router{
-		tree:                   tree.NewTree(),
+	r := &Router{
 		FixTrailingSlash:       true,
 		HandleMethodNotAllowed: true,
 		HandleOPTIONS:          true,
 	}
+	t := newTable()
+	t.notFoundFunc, t.redirectFunc = r.serveNotFound, r.serveRedirect
+	r.table.Store(t)
+	return r
 }
 
-func (r *Router) Handle(path string, handler http.Handler, httpMethod ...string) MiddlewareRegistrar {
-	methodCxt := &methodContext{handler, handler.ServeHTTP}
-	r.handle(path, methodCxt, httpMethod...)
+// Handle registers handler for path and methods in served table, it panics if route can not be registered,
+// routes must not be registered this way while serving, see Reload.
+// Parts of path in brackets are optional, so "[" and "]" are never static text, see tree.Variants
+func (r *Router) Handle(path string, handler http.Handler, httpMethod ...string) RouteEntry {
+	methodCxt := newMethodContext(handler, nil)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.handle(r.current(), "", path, methodCxt, r.Strict, httpMethod...); err != nil {
+		panic(err)
+	}
 	return methodCxt
 }
 
-func (r *Router) GET(path string, handler http.Handler) MiddlewareRegistrar {
+// TryHandle registers handler like Handle in strict mode, problems are returned as *RouteError instead of panicking
+func (r *Router) TryHandle(path string, handler http.Handler, httpMethod ...string) (RouteEntry, error) {
+	methodCxt := newMethodContext(handler, nil)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.handle(r.current(), "", path, methodCxt, true, httpMethod...); err != nil {
+		return nil, err
+	}
+	return methodCxt, nil
+}
+
+func (r *Router) GET(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "GET")
 }
 
-func (r *Router) POST(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) POST(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "POST")
 }
 
-func (r *Router) PUT(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) PUT(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "PUT")
 }
 
-func (r *Router) DELETE(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) DELETE(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "DELETE")
 }
 
-func (r *Router) OPTIONS(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) OPTIONS(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "OPTIONS")
 }
 
-func (r *Router) HEAD(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) HEAD(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "HEAD")
 }
 
-func (r *Router) PATCH(path string, handler http.Handler) MiddlewareRegistrar {
+func (r *Router) PATCH(path string, handler http.Handler) RouteEntry {
 	return r.Handle(path, handler, "PATCH")
 }
 
 func (r *Router) Group(path string, groupFunc func(routeRegistrar RouteRegistrar)) MiddlewareRegistrar {
 	group := newGroup(path)
 	groupFunc(group)
-	routes := group.root().getRoutes()
-	for path, m := range routes {
-		for method, methodCtx := range m {
-			r.handle(path, methodCtx, method)
+	r.handleGroup(group)
+	return group
+}
+
+func (r *Router) handleGroup(group *group) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.handleGroupTo(r.current(), group); err != nil {
+		panic(err)
+	}
+}
+
+func (r *Router) handleGroupTo(t *Table, group *group) error {
+	for _, route := range group.root().getRoutes() {
+		if err := r.handle(t, route.host, route.path, route.methodCtx, r.Strict, route.method); err != nil {
+			return err
 		}
 	}
-	return group
+	return nil
 }
 
-func (r *Router) handle(path string, methodCtx *methodContext, httpMethod ...string) {
-	r.tree.AddThen(path, func(context interface{}) interface{} {
-		var route Route
-		if r, ok := context.(Route); ok {
-			route = r
-		} else {
-			route = make(Route)
+// handle adds route of each variant of path to table, pattern errors of tree are only returned in strict mode,
+// otherwise tree panics
+func (r *Router) handle(t *Table, host string, path string, methodCtx *methodContext, strict bool, httpMethod ...string) error {
+	for _, m := range httpMethod {
+		if m != mountMethod && !methodRegexp.MatchString(m) {
+			return &RouteError{Method: m, Host: host, Pattern: path, Err: ErrInvalidMethod}
+		}
+	}
+	variants, err := tree.Variants(path)
+	if err != nil {
+		pe := err.(*tree.PatternError)
+		return &RouteError{Host: host, Pattern: path, Err: pe.Err, Detail: pe.Detail}
+	}
+	var h *hostRoutes
+	hostAdded := true
+	if host != "" {
+		if h, hostAdded, err = t.hostRoutes(host); err != nil {
+			return err
+		}
+	}
+	trie := t.tree
+	if h != nil {
+		trie = h.tree
+	}
+	if prev, ok := t.names[methodCtx.name]; ok && methodCtx.name != "" { //checked first, nothing is added then
+		if node := trie.Find(variants[0]); node == nil || prev.pattern() != host+node.FullPathPattern() {
+			return &RouteError{Host: host, Pattern: path, Err: ErrDuplicateName,
+				Detail: "'" + methodCtx.name + "' is used by '" + prev.pattern() + "'"}
 		}
+	}
+	nodes := make([]tree.NodeInterface, len(variants))
+	urlPatterns := make([]*urlPattern, len(variants))
+	restores := make([]func(), 0, len(variants))
+	for i, variant := range variants {
+		restore := r.restoreFunc(trie, variant, httpMethod)
+		if nodes[i], err = r.handleVariant(t, h, variant, methodCtx, strict, httpMethod...); err != nil {
+			for j := len(restores) - 1; j >= 0; j-- { //variants added before are removed again
+				restores[j]()
+			}
+			if re, ok := err.(*RouteError); ok && variant != path {
+				re.Pattern, re.Variant = path, variant
+			}
+			return err
+		}
+		restores = append(restores, restore)
+		urlPatterns[i] = newURLPattern(nodes[i].FullPathPattern())
+	}
+	if !hostAdded {
+		t.addHostRoutes(h)
+	}
+	methodCtx.host, methodCtx.urlPatterns = host, urlPatterns
+	methodCtx.router, methodCtx.table = r, t
+	if methodCtx.name != "" {
+		return t.addName(methodCtx.name, methodCtx)
+	}
+	return nil
+}
+
+// restoreFunc returns func restoring methods of route of path in trie as they are before they are added,
+// path is removed if it has no route yet
+func (r *Router) restoreFunc(trie tree.TrieInterface, path string, httpMethod []string) func() {
+	node := trie.Find(path)
+	if node == nil {
+		return func() { trie.Remove(path) }
+	}
+	route := node.Context().(*Route)
+	prev := route.clone()
+	return func() {
 		for _, m := range httpMethod {
-			if matches, err := regexp.MatchString("^[A-Z]+(-[A-Z]+)*$", m); !matches || err != nil {
-				panic("http method '" + m + "' is not valid")
+			if m == mountMethod {
+				route.mount, route.mountSegments = prev.mount, prev.mountSegments
+			} else if methodCtx, ok := prev.methods[m]; ok {
+				route.methods[m] = methodCtx
+			} else {
+				delete(route.methods, m)
+			}
+		}
+		route.compose(r)
+	}
+}
+
+// handleVariant adds route of path to tree of h, or to tree of routes not bound to any host if h is nil,
+// route is validated before tree is touched, so that table is left unchanged by errors
+func (r *Router) handleVariant(t *Table, h *hostRoutes, path string, methodCtx *methodContext, strict bool, httpMethod ...string) (tree.NodeInterface, error) {
+	trie, host := t.tree, ""
+	var hostConverters []tree.ConvertFunc
+	if h != nil {
+		segments, _ := parsePattern(path) //errors of path are reported by tree
+		for _, segment := range segments {
+			for _, hostParam := range h.params {
+				if segment.param && segment.name != "" && segment.name == hostParam {
+					return nil, &RouteError{Host: h.pattern, Pattern: path, Err: tree.ErrDuplicateParam,
+						Detail: "'" + hostParam + "' is also a param of host"}
+				}
+			}
+		}
+		trie, host, hostConverters = h.tree, h.pattern, h.converters
+	}
+	if strict {
+		if node := trie.Find(path); node != nil {
+			if route, ok := node.Context().(*Route); ok {
+				for _, m := range httpMethod {
+					prev, ok := route.methods[m]
+					if m == mountMethod {
+						prev, ok = route.mount, route.mount != nil
+					}
+					if ok && prev != methodCtx {
+						return nil, &RouteError{Method: m, Host: host, Pattern: path, Err: ErrDuplicateRoute}
+					}
+				}
+			}
+		}
+	}
+	var route *Route
+	addRoute := func(context interface{}) interface{} {
+		var ok bool
+		if route, ok = context.(*Route); !ok {
+			route = newRoute()
+		}
+		for _, m := range httpMethod {
+			if m == mountMethod {
+				route.mount = methodCtx
+			} else {
+				route.methods[m] = methodCtx
 			}
-			route[m] = methodCtx
 		}
 		return route
-	})
+	}
+	var node tree.NodeInterface
+	if strict {
+		var err error
+		node, err = trie.TryAddThen(path, addRoute)
+		if pe, ok := err.(*tree.PatternError); ok {
+			return nil, &RouteError{Host: host, Pattern: path, Err: pe.Err, Detail: pe.Detail}
+		} else if err != nil {
+			return nil, err
+		}
+	} else {
+		node = trie.AddThen(path, addRoute)
+	}
+	for _, m := range httpMethod {
+		if m == mountMethod {
+			route.mountSegments = countSegments(path)
+		}
+	}
+	methodCtx.router, methodCtx.table = r, t
+	route.converters = append([]tree.ConvertFunc(nil), hostConverters...)
+	for _, typ := range node.ParamTypes() {
+		route.converters = append(route.converters, tree.Converter(typ))
+	}
+	route.node, route.host, route.pattern = node, host, node.FullPathPattern()
+	route.compose(r)
+	return node, nil
+}
+
+// Unhandle removes methods of path from routes not bound to any host and from routes of each host,
+// all methods and handlers mounted at path are removed if no method is given, mountMethod "*" removes mounted
+// handlers only. Path is removed once no method is left, it must be written as registered, placeholders and
+// optional parts included. Routes are removed from a copy of served table which is swapped in,
+// it reports whether any method was removed
+func (r *Router) Unhandle(path string, httpMethod ...string) bool {
+	variants, err := tree.Variants(path)
+	if err != nil {
+		return false
+	}
+	unmount := len(httpMethod) == 0
+	for _, m := range httpMethod {
+		unmount = unmount || m == mountMethod
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.current().clone()
+	trees := []tree.TrieInterface{t.tree}
+	for _, h := range t.hosts {
+		trees = append(trees, h.tree)
+	}
+	removed := false
+	for _, trie := range trees {
+		for _, variant := range variants {
+			removed = r.unhandleVariant(trie, variant, unmount, httpMethod...) || removed
+		}
+		if unmount { //other paths of handler mounted at path, see mountPaths
+			for _, mountPath := range mountPaths(path)[1:] {
+				removed = r.unhandleVariant(trie, mountPath, true, mountMethod) || removed
+			}
+		}
+	}
+	hosts := t.hosts[:0]
+	for _, h := range t.hosts {
+		if h.tree.Walk(func(node tree.NodeInterface) error { return errStopWalk }) != nil { //routes are left to host
+			hosts = append(hosts, h)
+		}
+	}
+	t.hosts = hosts
+	for name, named := range t.names {
+		if namesake := named.namesake(t); namesake != nil {
+			t.names[name] = namesake
+		} else {
+			delete(t.names, name)
+		}
+	}
+	r.table.Store(t)
+	return removed
+}
+
+var errStopWalk = errors.New("stop walk")
+
+// unhandleVariant removes methods of path from trie, mounted handler is removed if unmount
+func (r *Router) unhandleVariant(trie tree.TrieInterface, path string, unmount bool, httpMethod ...string) bool {
+	node := trie.Find(path)
+	if node == nil {
+		return false
+	}
+	route, ok := node.Context().(*Route)
+	if !ok {
+		return false
+	}
+	if len(httpMethod) == 0 {
+		httpMethod = route.Methods()
+	}
+	removed := false
+	for _, m := range httpMethod {
+		if _, ok := route.methods[m]; ok {
+			delete(route.methods, m)
+			removed = true
+		}
+	}
+	if unmount && route.mount != nil {
+		route.mount = nil
+		removed = true
+	}
+	if len(route.methods) == 0 && route.mount == nil {
+		trie.Remove(path)
+	} else {
+		route.compose(r)
+	}
+	return removed
 }
 
 func uniqueAppend(a []string, s string) []string {
@@ -169,9 +686,3 @@ func uniqueAppend(a []string, s string) []string {
 	a = append(a, s)
 	return a
 }
-
-func (r *Router) recover(w http.ResponseWriter, req *http.Request) {
-	if rev := recover(); rev != nil {
-		r.PanicFunc(rev)(w, req)
-	}
-}
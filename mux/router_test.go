@@ -1,11 +1,687 @@
 package mux
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/mfantcy/rdx-router/tree"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNothing(t *testing.T) {
 	assert.True(t, true)
 }
+
+func TestRouter_GroupMiddleware(t *testing.T) {
+	var trace []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace = append(trace, "handler")
+	})
+	r := NewRouter()
+	r.GET("/plain", handler)
+	api := r.Group("/api", func(api RouteRegistrar) {
+		api.GET("/users", handler)
+		api.Use(tagMiddleware("api", &trace))
+		api.Group("/v1", func(v1 RouteRegistrar) {
+			v1.Use(tagMiddleware("v1", &trace))
+			v1.GET("/items", handler)
+		})
+	})
+
+	serveRequest(r, "GET", "/plain")
+	assert.Equal(t, []string{"handler"}, trace)
+
+	trace = nil
+	serveRequest(r, "GET", "/api/users")
+	assert.Equal(t, []string{"api", "handler"}, trace)
+
+	trace = nil
+	serveRequest(r, "GET", "/api/v1/items")
+	assert.Equal(t, []string{"api", "v1", "handler"}, trace)
+
+	trace = nil
+	api.Use(tagMiddleware("late", &trace))
+	serveRequest(r, "GET", "/api/v1/items")
+	assert.Equal(t, []string{"late", "v1", "handler"}, trace)
+}
+
+type nopResponseWriter struct {
+	header http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *nopResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *nopResponseWriter) WriteHeader(statusCode int) {}
+
+func passMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req)
+	})
+}
+
+// nopHandler is handler of routes of which only routing is tested
+var nopHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+
+// serveRequest serves request of method to target by r, host of request is taken from target if it is an absolute URL
+func serveRequest(r http.Handler, method string, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func newBenchRouter() *Router {
+	r := NewRouter()
+	r.GET("/", nopHandler)
+	r.GET("/users", nopHandler)
+	r.GET("/users/{id:[0-9]+}", nopHandler)
+	r.GET("/users/{id}/profile", nopHandler)
+	r.POST("/users/new", nopHandler)
+	r.GET("/static/{path...}", nopHandler)
+	r.Group("/api", func(api RouteRegistrar) {
+		api.Use(passMiddleware)
+		api.GET("/status", nopHandler)
+	})
+	r.Use(passMiddleware, passMiddleware)
+	return r
+}
+
+func TestRouter_UseShouldComposeGlobalMiddleware(t *testing.T) {
+	var trace []string
+	r := NewRouter()
+	r.GET("/a", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace = append(trace, "handler")
+	}))
+	r.Use(tagMiddleware("global", &trace))
+	r.GET("/b", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		trace = append(trace, "handler")
+	}))
+
+	for _, path := range []string{"/a", "/b"} {
+		trace = nil
+		serveRequest(r, "GET", path)
+		assert.Equal(t, []string{"global", "handler"}, trace)
+	}
+
+	trace = nil
+	w := serveRequest(r, "GET", "/c")
+	assert.Equal(t, []string{"global"}, trace)
+	assert.Equal(t, 404, w.Code)
+
+	trace = nil
+	w = serveRequest(r, "DELETE", "/a")
+	assert.Equal(t, []string{"global"}, trace)
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+
+	trace = nil
+	w = serveRequest(r, "OPTIONS", "/a")
+	assert.Equal(t, []string{"global"}, trace)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestRouter_ServeHTTPStaticRouteShouldNotAllocate(t *testing.T) {
+	r := newBenchRouter()
+	w := &nopResponseWriter{http.Header{}}
+	for _, path := range []string{"/", "/users", "/users/new", "/api/status"} {
+		method := "GET"
+		if path == "/users/new" {
+			method = "POST"
+		}
+		req := httptest.NewRequest(method, path, nil)
+		allocs := testing.AllocsPerRun(100, func() {
+			r.ServeHTTP(w, req)
+		})
+		assert.Equal(t, float64(0), allocs, path)
+	}
+}
+
+func BenchmarkRouter_ServeHTTPStatic(b *testing.B) {
+	r := newBenchRouter()
+	w := &nopResponseWriter{http.Header{}}
+	req := httptest.NewRequest("GET", "/users", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkRouter_ServeHTTPParams(b *testing.B) {
+	r := newBenchRouter()
+	w := &nopResponseWriter{http.Header{}}
+	req := httptest.NewRequest("GET", "/users/123/profile", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkRouter_ServeHTTPNotFound(b *testing.B) {
+	r := newBenchRouter()
+	w := &nopResponseWriter{http.Header{}}
+	req := httptest.NewRequest("GET", "/not/found", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func TestRouter_TryHandle(t *testing.T) {
+	r := NewRouter()
+	_, err := r.TryHandle("/users/{id:[0-9]+}", nopHandler, "GET")
+	assert.NoError(t, err)
+	_, err = r.TryHandle("/users/{id:[0-9]+}", nopHandler, "POST")
+	assert.NoError(t, err)
+
+	_, err = r.TryHandle("/users/{id:[0-9]+}", nopHandler, "GET")
+	assert.Equal(t, &RouteError{Method: "GET", Pattern: "/users/{id:[0-9]+}", Err: ErrDuplicateRoute}, err)
+	assert.Equal(t, "route 'GET /users/{id:[0-9]+}': method is already registered", err.Error())
+
+	_, err = r.TryHandle("/users/{id:\\d+}", nopHandler, "PUT")
+	assert.True(t, errors.Is(err, tree.ErrAmbiguousPattern))
+	_, err = r.TryHandle("/users/{id:int}", nopHandler, "PUT")
+	assert.True(t, errors.Is(err, tree.ErrAmbiguousPattern))
+	_, err = r.TryHandle("/posts[/{id:\\d+}]", nopHandler, "GET")
+	assert.NoError(t, err)
+	_, err = r.TryHandle("/posts[/{slug:[0-9a-z]+}]", nopHandler, "PUT")
+	if assert.IsType(t, (*RouteError)(nil), err) {
+		assert.Equal(t, "/posts[/{slug:[0-9a-z]+}]", err.(*RouteError).Pattern)
+		assert.Equal(t, "/posts/{slug:[0-9a-z]+}", err.(*RouteError).Variant)
+		assert.True(t, errors.Is(err, tree.ErrAmbiguousPattern))
+	}
+	_, err = r.TryHandle("/users/{name:[0-9]+}", nopHandler, "PUT")
+	assert.True(t, errors.Is(err, tree.ErrParamConflict))
+	_, err = r.TryHandle("/users/{id:[0-9}", nopHandler, "PUT")
+	assert.True(t, errors.Is(err, tree.ErrInvalidRegexp))
+	_, err = r.TryHandle("/users", nopHandler, "get")
+	assert.True(t, errors.Is(err, ErrInvalidMethod))
+	assert.IsType(t, (*RouteError)(nil), err)
+
+	w := serveRequest(r, "PUT", "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRouter_TryHandleErrorShouldLeaveTableUnchanged(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/{id:[0-9]+}", nopHandler).Name("user")
+	r.Host("api.example.com", func(host RouteRegistrar) {
+		host.GET("/status", nopHandler)
+	})
+	routes := r.Routes()
+	hosts := len(r.current().hosts)
+
+	cases := []func() error{
+		func() error {
+			_, err := r.TryHandle("/users/{id:[0-9]+}/files/{name:[a-}", nopHandler, "GET")
+			return err
+		},
+		func() error {
+			_, err := r.TryHandle("/users/{id:[0-9]+}/{id}", nopHandler, "GET")
+			return err
+		},
+		func() error {
+			_, err := r.TryHandle("/users/{id:\\d+}/posts", nopHandler, "GET")
+			return err
+		},
+		func() error {
+			_, err := r.TryHandle("/users/{id:[0-9]+}", nopHandler, "GET")
+			return err
+		},
+		func() error {
+			_, err := r.TryHandle("/users/{id:[0-9]+}[/posts]", nopHandler, "GET")
+			return err
+		},
+		func() error {
+			r.Strict = true
+			defer func() { r.Strict = false }()
+			return tryPanic(func() {
+				r.Host("{tenant}.example.com", func(host RouteRegistrar) {
+					host.GET("/{tenant}", nopHandler)
+				})
+			})
+		},
+		func() error {
+			return tryPanic(func() {
+				r.Host("www.example.com", func(host RouteRegistrar) {
+					host.GET("/{a}{b}", nopHandler)
+				})
+			})
+		},
+		func() error {
+			return tryPanic(func() {
+				r.Group("/posts", func(group RouteRegistrar) {
+					group.GET("/{id}", nopHandler).Name("user")
+				})
+			})
+		},
+	}
+	for i, try := range cases {
+		assert.Error(t, try(), i)
+		assert.Equal(t, routes, r.Routes(), i)
+		assert.Len(t, r.current().hosts, hosts, i)
+		assert.Nil(t, r.current().tree.Find("/posts/{id}"), i)
+	}
+}
+
+func tryPanic(f func()) (err error) {
+	defer func() {
+		if rev := recover(); rev != nil {
+			err = rev.(error)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestRouter_Strict(t *testing.T) {
+	r := NewRouter()
+	r.GET("/a", nopHandler)
+	assert.NotPanics(t, func() { r.GET("/a", nopHandler) })
+
+	r.Strict = true
+	assert.Panics(t, func() { r.GET("/a", nopHandler) })
+	assert.Panics(t, func() {
+		r.Group("/", func(group RouteRegistrar) {
+			group.GET("/a", nopHandler)
+		})
+	})
+	assert.Panics(t, func() {
+		r.Host("{id}.example.com", func(host RouteRegistrar) {
+			host.GET("/{id}", nopHandler)
+		})
+	})
+	assert.NotPanics(t, func() { r.POST("/a", nopHandler) })
+}
+
+func TestRouter_HandleHEAD(t *testing.T) {
+	r := NewRouter()
+	r.GET("/a", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Method", req.Method)
+		w.Write([]byte("hello"))
+	}))
+	r.GET("/b", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("0123456789"))
+	}))
+	r.POST("/c", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+
+	w := serveRequest(r, "HEAD", "/a")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+
+	r.HandleHEAD = true
+	w = serveRequest(r, "HEAD", "/a")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "HEAD", w.Header().Get("X-Method"))
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	w = serveRequest(r, "HEAD", "/b")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	w = serveRequest(r, "HEAD", "/c")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "OPTIONS, POST", w.Header().Get("Allow"))
+
+	r.GET("/created", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	w = serveRequest(r, "HEAD", "/created")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	r.GET("/flush", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("event"))
+		http.NewResponseController(w).Flush()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	w = serveRequest(r, "HEAD", "/flush")
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, w.Flushed)
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	r.GET("/panic", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		panic("boom")
+	}))
+	w = httptest.NewRecorder()
+	assert.Panics(t, func() { r.ServeHTTP(w, httptest.NewRequest("HEAD", "/panic", nil)) })
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = serveRequest(r, "OPTIONS", "/a")
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+
+	r.HandleOPTIONS = false
+	w = serveRequest(r, "DELETE", "/a")
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+}
+
+func TestRouter_HandleHEADShouldSetContentLengthOfServer(t *testing.T) {
+	r := NewRouter()
+	r.HandleHEAD = true
+	r.GET("/created", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Head(server.URL + "/created")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int64(5), resp.ContentLength)
+}
+
+func TestRouter_AllowHeaderShouldBeSorted(t *testing.T) {
+	var allowed []string
+	r := NewRouter()
+	r.HandleHEAD = true
+	r.Handle("/a", nopHandler, "PUT", "GET", "POST", "DELETE", "PATCH")
+	r.MethodNotAllowedHandler = func(w http.ResponseWriter, req *http.Request) {
+		allowed = AllowedMethods(req)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+	for i := 0; i < 10; i++ {
+		w := serveRequest(r, "TRACE", "/a")
+		assert.Equal(t, "DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT", w.Header().Get("Allow"))
+		assert.Equal(t, []string{"DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT"}, allowed)
+	}
+	assert.Equal(t, []string{"DELETE", "GET", "PATCH", "POST", "PUT"}, r.Routes()[0].Methods)
+	assert.Empty(t, AllowedMethods(httptest.NewRequest("GET", "/a", nil)))
+}
+
+func TestRouter_Unhandle(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/{id}", nopHandler).Name("user")
+	r.PUT("/users/{id}", nopHandler).Name("user")
+	r.DELETE("/users/{id}", nopHandler).Name("delete")
+	r.GET("/users/{id}/posts", nopHandler)
+
+	assert.False(t, r.Unhandle("/users/{name}"))
+	assert.False(t, r.Unhandle("/users/{id}", "POST"))
+
+	assert.True(t, r.Unhandle("/users/{id}", "DELETE"))
+	w := serveRequest(r, "DELETE", "/users/1")
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "GET, OPTIONS, PUT", w.Header().Get("Allow"))
+	_, err := r.URL("delete", "id", "1")
+	assert.Error(t, err)
+
+	assert.True(t, r.Unhandle("/users/{id}", "GET"))
+	_, err = r.URL("user", "id", "1")
+	assert.NoError(t, err)
+
+	assert.True(t, r.Unhandle("/users/{id}"))
+	assert.Equal(t, 404, serveRequest(r, "PUT", "/users/1").Code)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/users/1/posts").Code)
+	_, err = r.URL("user", "id", "1")
+	assert.Error(t, err)
+	assert.Equal(t, []RouteInfo{{Pattern: "/users/{id}/posts", Methods: []string{"GET"}, Params: []string{"id"}}}, r.Routes())
+
+	r.GET("/users/{id}", nopHandler)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/users/1").Code)
+}
+
+func TestRouter_OptionalParams(t *testing.T) {
+	var page string
+	var matched bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		page, matched = RequestParams(req).Lookup("page")
+	})
+	r := NewRouter()
+	r.GET("/posts/{page?:int}", handler).Name("posts")
+	r.GET("/tags/{tag}/{page?}", handler).Name("tag")
+	r.Group("/archive", func(rr RouteRegistrar) {
+		rr.GET("[/{year:int}[/{month:int}]]", handler).Name("archive")
+	})
+
+	assert.Equal(t, 200, serveRequest(r, "GET", "/posts/2").Code)
+	assert.Equal(t, "2", page)
+	assert.True(t, matched)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/posts").Code)
+	assert.False(t, matched)
+	assert.Equal(t, 404, serveRequest(r, "GET", "/posts/x").Code)
+	assert.Equal(t, 405, serveRequest(r, "POST", "/posts").Code)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/archive/2020/1").Code)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/archive").Code)
+
+	u, err := r.URL("posts")
+	assert.NoError(t, err)
+	assert.Equal(t, "/posts", u)
+	u, err = r.URL("posts", "page", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, "/posts/3", u)
+	u, err = r.URL("tag", "tag", "go")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tags/go", u)
+	u, err = r.URL("archive", "year", "2020")
+	assert.NoError(t, err)
+	assert.Equal(t, "/archive/2020", u)
+	_, err = r.URL("tag", "page", "1")
+	assert.Error(t, err)
+
+	assert.True(t, r.Unhandle("/posts[/{page:int}]"))
+	assert.Equal(t, 404, serveRequest(r, "GET", "/posts").Code)
+	assert.Equal(t, 404, serveRequest(r, "GET", "/posts/2").Code)
+	_, err = r.URL("posts")
+	assert.Error(t, err)
+
+	_, err = r.TryHandle("/a[/{b}", handler, "GET")
+	assert.True(t, errors.Is(err, tree.ErrInvalidOptional))
+}
+
+func TestRouter_RedirectToCanonicalPath(t *testing.T) {
+	var id string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id = RequestParams(req).ValueOf("id")
+	})
+	r := NewRouter()
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = true
+	r.GET("/Users/{id}", handler)
+	r.POST("/users/{id}/posts/", handler)
+	r.GET("/about", handler)
+
+	cases := []struct {
+		method, path, location string
+		code                   int
+	}{
+		{"GET", "/about/", "/about", 301},
+		{"GET", "/users/Bob", "/Users/Bob", 301},
+		{"GET", "/users/Bob?tab=1", "/Users/Bob?tab=1", 301},
+		{"GET", "//about/../Users/./Bob", "/Users/Bob", 301},
+		{"POST", "/users/1/posts", "/users/1/posts/", 308},
+		{"POST", "/USERS/1//POSTS", "/users/1/posts/", 308},
+	}
+	for _, c := range cases {
+		w := serveRequest(r, c.method, c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.path)
+	}
+	assert.Equal(t, 404, serveRequest(r, "GET", "/contact").Code)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/Users/Bob").Code)
+
+	r.RedirectTrailingSlash, r.RedirectFixedPath = false, false
+	assert.Equal(t, 200, serveRequest(r, "GET", "/about/").Code)
+	assert.Equal(t, 404, serveRequest(r, "GET", "/users/Bob").Code)
+
+	r.CaseInsensitive = true
+	w := serveRequest(r, "GET", "/USERS/Bob")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "Bob", id)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/ABOUT/").Code)
+	assert.Equal(t, 405, serveRequest(r, "GET", "/users/1/POSTS/").Code)
+}
+
+func TestRouter_RedirectShouldSkipPathsNotRooted(t *testing.T) {
+	r := NewRouter()
+	r.RedirectTrailingSlash = true
+	r.RedirectFixedPath = true
+	r.Handle("/{path...}", nopHandler, "OPTIONS")
+
+	w := serveRequest(r, "OPTIONS", "*")
+	assert.Equal(t, 404, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestRouter_RedirectShouldPassMiddlewareAndKeepMountPrefix(t *testing.T) {
+	sub := NewRouter()
+	sub.RedirectTrailingSlash = true
+	sub.GET("/about", nopHandler)
+	sub.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Sub", "1")
+			next.ServeHTTP(w, req)
+		})
+	})
+	nested := NewRouter()
+	nested.Mount("/api", sub)
+	r := NewRouter()
+	r.Mount("/v1", nested)
+	r.Mount("/t/{tenant}", sub)
+	r.Mount("/raw", sub)
+
+	cases := [][2]string{
+		{"/v1/api/about/", "/v1/api/about"},
+		{"/t/acme/about/?q=1", "/t/acme/about?q=1"},
+		{"/raw/about/", "/raw/about"},
+	}
+	for _, c := range cases {
+		w := serveRequest(r, "GET", c[0])
+		assert.Equal(t, 301, w.Code, c[0])
+		assert.Equal(t, c[1], w.Header().Get("Location"), c[0])
+		assert.Equal(t, "1", w.Header().Get("X-Sub"), c[0])
+	}
+
+	r.UseRawPath, sub.UseRawPath = true, true
+	w := serveRequest(r, "GET", "/t/a%2Fb/about/")
+	assert.Equal(t, "/t/a%2Fb/about", w.Header().Get("Location"))
+}
+
+func TestRouter_UseRawPath(t *testing.T) {
+	var values []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		values = nil
+		params := RequestParams(req)
+		for i := 0; i < params.Count(); i++ {
+			values = append(values, params.Value(i))
+		}
+	})
+	sub := NewRouter()
+	sub.UseRawPath = true
+	sub.GET("/files/{name}", handler)
+	r := NewRouter()
+	r.UseRawPath = true
+	r.GET("/repos/{id}/issues", handler).Name("issues")
+	r.GET("/items/{id}", handler)
+	r.GET("/static/{path...}", handler)
+	r.Mount("/mounted/{id}", sub)
+
+	assert.Equal(t, 200, serveRequest(r, "GET", "/repos/a%2Fb/issues").Code)
+	assert.Equal(t, []string{"a/b"}, values)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/items/a%3Fb%23c%20d").Code)
+	assert.Equal(t, []string{"a?b#c d"}, values)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/static/css/a%2Fb.css").Code)
+	assert.Equal(t, []string{"css/a/b.css"}, values)
+	assert.Equal(t, 200, serveRequest(r, "GET", "/mounted/a%2Fb/files/c%2Fd").Code)
+	assert.Equal(t, []string{"a/b", "c/d"}, values)
+
+	u, err := r.URL("issues", "id", "a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "/repos/a%2Fb/issues", u)
+
+	r.UseRawPath = false
+	assert.Equal(t, 404, serveRequest(r, "GET", "/repos/a%2Fb/issues").Code)
+	_, err = r.URL("issues", "id", "a/b")
+	assert.Error(t, err)
+}
+
+func TestRouter_UseWhileServingShouldNotRace(t *testing.T) {
+	middleware := func(next http.Handler) http.Handler { return next }
+	r := NewRouter()
+	entry := r.GET("/users/{id}", nopHandler)
+	apiGroup := r.Group("/api", func(api RouteRegistrar) {
+		api.GET("/items", nopHandler)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			for _, path := range []string{"/users/1", "/api/items", "/missing"} {
+				serveRequest(r, "GET", path)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		r.Use(middleware)
+		r.CORS(&CORSConfig{AllowedOrigins: []string{"*"}})
+		entry.Use(middleware)
+		apiGroup.Use(middleware)
+		apiGroup.(*group).CORS(nil)
+	}
+	<-done
+
+	var trace []string
+	r.Use(tagMiddleware("global", &trace))
+	entry.Use(tagMiddleware("route", &trace))
+	apiGroup.Use(tagMiddleware("group", &trace))
+	serveRequest(r, "GET", "/users/1")
+	serveRequest(r, "GET", "/api/items")
+	serveRequest(r, "GET", "/missing")
+	assert.Equal(t, []string{"global", "route", "global", "group", "global"}, trace)
+}
+
+func TestRouter_UnhandleHostRoutesAndMounts(t *testing.T) {
+	r := NewRouter()
+	r.GET("/status", nopHandler)
+	r.Host("api.example.com", func(host RouteRegistrar) {
+		host.GET("/status", nopHandler).Name("api.status")
+		host.GET("/users", nopHandler)
+	})
+	r.Host("{tenant}.example.com", func(host RouteRegistrar) {
+		host.GET("/status", nopHandler)
+	})
+	r.Mount("/files", nopHandler)
+	served := r.current()
+
+	assert.True(t, r.Unhandle("/status"))
+	assert.Equal(t, 404, serveRequest(r, "GET", "http://api.example.com/status").Code)
+	assert.Equal(t, 404, serveRequest(r, "GET", "http://acme.example.com/status").Code)
+	assert.Equal(t, 404, serveRequest(r, "GET", "http://example.com/status").Code)
+	assert.Equal(t, 200, serveRequest(r, "GET", "http://api.example.com/users").Code)
+	assert.Len(t, r.current().hosts, 1)
+	_, err := r.URL("api.status")
+	assert.Error(t, err)
+
+	assert.False(t, r.Unhandle("/files", "GET"))
+	assert.Equal(t, 200, serveRequest(r, "GET", "http://example.com/files/a").Code)
+	assert.True(t, r.Unhandle("/files"))
+	for _, path := range []string{"/files", "/files/", "/files/a"} {
+		assert.Equal(t, 404, serveRequest(r, "GET", "http://example.com"+path).Code, path)
+	}
+
+	//the served table is left as it is for requests in flight
+	assert.NotNil(t, served.tree.Find("/status"))
+	assert.NotNil(t, served.tree.Find("/files/{...}"))
+	assert.Len(t, served.hosts, 2)
+}
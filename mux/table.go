@@ -0,0 +1,156 @@
+package mux
+
+import (
+	"net/http"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
+
+// Table holds routes of Router, a table compiled by Router.Compile is not modified once it is served,
+// so that it can be swapped while serving without locks on lookups
+type Table struct {
+	tree  tree.TrieInterface
+	hosts []*hostRoutes
+	names map[string]*methodContext
+	//notFoundFunc and redirectFunc are composed with global middleware like handlers of routes
+	notFoundFunc http.HandlerFunc
+	redirectFunc http.HandlerFunc
+	//routeAware is copied from router, see Router.UseRouteAware
+	routeAware bool
+	//generation of router the routes are composed with
+	generation int
+}
+
+func newTable() *Table {
+	return &Table{tree: tree.NewTree(), names: make(map[string]*methodContext)}
+}
+
+// walk calls walkFunc for routes not bound to any host, then for routes of each host
+func (t *Table) walk(walkFunc tree.WalkFunc) error {
+	if err := t.tree.Walk(walkFunc); err != nil {
+		return err
+	}
+	for _, h := range t.hosts {
+		if err := h.tree.Walk(walkFunc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clone copies table with its routes, so that routes can be composed or removed without touching table,
+// method contexts registered to table are moved to the copy
+func (t *Table) clone() *Table {
+	c := &Table{tree: cloneRoutes(t.tree), names: make(map[string]*methodContext, len(t.names)),
+		notFoundFunc: t.notFoundFunc, redirectFunc: t.redirectFunc, routeAware: t.routeAware, generation: t.generation}
+	for _, h := range t.hosts {
+		hc := *h
+		hc.tree = cloneRoutes(h.tree)
+		c.hosts = append(c.hosts, &hc)
+	}
+	for name, methodCtx := range t.names {
+		c.names[name] = methodCtx
+	}
+	c.walk(func(node tree.NodeInterface) error {
+		route, ok := node.Context().(*Route)
+		if !ok {
+			return nil
+		}
+		route.node = node
+		for _, methodCtx := range route.methods {
+			if methodCtx.table == t {
+				methodCtx.table = c
+			}
+		}
+		if route.mount != nil && route.mount.table == t {
+			route.mount.table = c
+		}
+		return nil
+	})
+	return c
+}
+
+func cloneRoutes(trie tree.TrieInterface) tree.TrieInterface {
+	return trie.Clone(func(context interface{}) interface{} {
+		if route, ok := context.(*Route); ok {
+			return route.clone()
+		}
+		return context
+	})
+}
+
+// route returns route of pattern bound to host, nil if it is not registered
+func (t *Table) route(host string, pattern string) *Route {
+	trie := t.tree
+	if host != "" {
+		trie = nil
+		for _, h := range t.hosts {
+			if h.pattern == host {
+				trie = h.tree
+			}
+		}
+		if trie == nil {
+			return nil
+		}
+	}
+	if node := trie.Find(pattern); node != nil {
+		route, _ := node.Context().(*Route)
+		return route
+	}
+	return nil
+}
+
+func (t *Table) addName(name string, methodCtx *methodContext) error {
+	if prev, ok := t.names[name]; ok && prev.pattern() != methodCtx.pattern() {
+		return &RouteError{Host: methodCtx.host, Pattern: methodCtx.urlPatterns[0].pattern, Err: ErrDuplicateName,
+			Detail: "'" + name + "' is used by '" + prev.pattern() + "'"}
+	}
+	t.names[name] = methodCtx
+	return nil
+}
+
+// Compile builds a new table by registerFunc without touching the served one,
+// registration errors are returned instead of panicking
+func (r *Router) Compile(registerFunc func(routeRegistrar RouteRegistrar)) (table *Table, err error) {
+	group := newGroup("")
+	registerFunc(group)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer func() {
+		if rev := recover(); rev != nil {
+			if err, _ = rev.(error); err == nil {
+				panic(rev)
+			}
+			table = nil
+		}
+	}()
+	table = newTable()
+	if err = r.handleGroupTo(table, group); err != nil {
+		return nil, err
+	}
+	r.composeTable(table)
+	return table, nil
+}
+
+// Swap atomically replaces the served table, requests in flight keep using the previous table which is returned
+func (r *Router) Swap(table *Table) *Table {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if table.generation != r.generation { //middleware changed since table was compiled, table may be served before
+		table = table.clone()
+		r.composeTable(table)
+	}
+	prev := r.current()
+	r.table.Store(table)
+	return prev
+}
+
+// Reload compiles a new table by registerFunc and swaps it in, the served table is kept if registration fails
+func (r *Router) Reload(registerFunc func(routeRegistrar RouteRegistrar)) error {
+	table, err := r.Compile(registerFunc)
+	if err != nil {
+		return err
+	}
+	r.Swap(table)
+	return nil
+}
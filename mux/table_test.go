@@ -0,0 +1,91 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/tree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Reload(t *testing.T) {
+	echo := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+	serve := func(r *Router, path string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+	r := NewRouter()
+	r.GET("/a", echo("a1")).Name("a")
+
+	err := r.Reload(func(routes RouteRegistrar) {
+		routes.GET("/a", echo("a2"))
+		routes.GET("/b/{id}", echo("b2")).Name("b")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a2", serve(r, "/a"))
+	assert.Equal(t, "b2", serve(r, "/b/1"))
+	u, err := r.URL("b", "id", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "/b/2", u)
+	_, err = r.URL("a")
+	assert.Error(t, err)
+
+	err = r.Reload(func(routes RouteRegistrar) {
+		routes.GET("/c/{id}/{id}", echo("c"))
+	})
+	assert.True(t, errors.Is(err, tree.ErrDuplicateParam))
+	err = r.Reload(func(routes RouteRegistrar) {
+		routes.GET("/c", echo("c")).Name("c")
+		routes.GET("/d", echo("d")).Name("c")
+	})
+	assert.True(t, errors.Is(err, ErrDuplicateName))
+	assert.Equal(t, "b2", serve(r, "/b/1"))
+
+	table, err := r.Compile(func(routes RouteRegistrar) {
+		routes.GET("/c", echo("c3"))
+	})
+	assert.NoError(t, err)
+	var trace []string
+	r.Use(tagMiddleware("global", &trace))
+	assert.Equal(t, "b2", serve(r, "/b/1"))
+	assert.Equal(t, "Not Found\n", serve(r, "/c"))
+	prev := r.Swap(table)
+	assert.Equal(t, "c3", serve(r, "/c"))
+	assert.Equal(t, []string{"global", "global", "global"}, trace)
+
+	r.Swap(prev)
+	assert.Equal(t, "b2", serve(r, "/b/1"))
+}
+
+func TestRouter_ReloadWhileServing(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	r := NewRouter()
+	r.GET("/users/{id}", handler)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+				assert.Equal(t, 200, w.Code)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, r.Reload(func(routes RouteRegistrar) {
+			routes.GET("/users/{id}", handler)
+			routes.POST("/users", handler)
+		}))
+	}
+	wg.Wait()
+}
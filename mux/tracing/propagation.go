@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+)
+
+// Headers of W3C Trace Context
+const (
+	TraceparentHeader = "Traceparent"
+	TracestateHeader  = "Tracestate"
+)
+
+// ParseTraceparent parses "traceparent" header, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+// fields appended by future versions are ignored
+func ParseTraceparent(value string) (sc SpanContext, ok bool) {
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	var version [1]byte
+	if !decodeLowerHex(version[:], value[:2]) || version[0] == 0xff {
+		return sc, false
+	}
+	if version[0] == 0 && len(value) != 55 || len(value) > 55 && value[55] != '-' {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeLowerHex(sc.TraceID[:], value[3:35]) || !decodeLowerHex(sc.SpanID[:], value[36:52]) ||
+		!decodeLowerHex(flags[:], value[53:55]) {
+		return SpanContext{}, false
+	}
+	sc.TraceFlags = flags[0]
+	sc.Remote = true
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// decodeLowerHex decodes src into dst, upper case digits are invalid in trace context
+func decodeLowerHex(dst []byte, src string) bool {
+	for i := 0; i < len(src); i++ {
+		if c := src[i]; 'A' <= c && c <= 'F' {
+			return false
+		}
+	}
+	n, err := hex.Decode(dst, []byte(src))
+	return err == nil && n == len(dst)
+}
+
+// Traceparent formats span context as "traceparent" header of version 00
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.TraceFlags})
+}
+
+// Extract returns span context of "traceparent" and "tracestate" headers
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, ok := ParseTraceparent(header.Get(TraceparentHeader))
+	if ok {
+		sc.TraceState = header.Get(TracestateHeader)
+	}
+	return sc, ok
+}
+
+// Inject sets "traceparent" and "tracestate" headers of span carried by ctx, e.g. on outgoing requests
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil || !span.SpanContext().IsValid() {
+		return
+	}
+	sc := span.SpanContext()
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	} else {
+		header.Del(TracestateHeader)
+	}
+}
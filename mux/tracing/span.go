@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace, it is valid unless all bytes are zero
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span of a trace, it is valid unless all bytes are zero
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// FlagsSampled is the trace flag of sampled spans
+const FlagsSampled byte = 0x01
+
+// SpanContext is the part of a span propagated to other services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags byte
+
+	//TraceState is "tracestate" header, it is passed on as it is
+	TraceState string
+
+	//Remote is set for span context extracted from request
+	Remote bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags&FlagsSampled != 0
+}
+
+// SpanKind is the role of span, values are those of OpenTelemetry
+type SpanKind int
+
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// StatusCode is the status of span, values are those of OpenTelemetry
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusError
+	StatusOK
+)
+
+// Attribute is a key value pair describing span or event
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Event is something happening during span, e.g. "exception"
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanData is a snapshot of ended span passed to Exporter
+type SpanData struct {
+	Name              string
+	Kind              SpanKind
+	SpanContext       SpanContext
+	Parent            SpanContext
+	StartTime         time.Time
+	EndTime           time.Time
+	Attributes        []Attribute
+	Events            []Event
+	Status            StatusCode
+	StatusDescription string
+}
+
+// Attribute returns value of attribute with key, the last one set wins
+func (d *SpanData) Attribute(key string) (interface{}, bool) {
+	for i := len(d.Attributes) - 1; i >= 0; i-- {
+		if d.Attributes[i].Key == key {
+			return d.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Span records an operation until End is called, it is safe for concurrent use
+type Span struct {
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+	tracer *Tracer
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Name = name
+	}
+}
+
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes = append(s.data.Attributes, attributes...)
+	}
+}
+
+func (s *Span) AddEvent(name string, attributes ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Events = append(s.data.Events, Event{Name: name, Time: time.Now(), Attributes: attributes})
+	}
+}
+
+// SetStatus sets status of span, description is kept for StatusError only, StatusOK is final
+func (s *Span) SetStatus(code StatusCode, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || s.data.Status == StatusOK || code == StatusUnset {
+		return
+	}
+	s.data.Status = code
+	if code == StatusError {
+		s.data.StatusDescription = description
+	} else {
+		s.data.StatusDescription = ""
+	}
+}
+
+// End ends span and exports it if it is sampled, later calls are ignored
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if data.SpanContext.IsSampled() && s.tracer.Exporter != nil {
+		s.tracer.Exporter.ExportSpan(data)
+	}
+}
+
+type spanCtxKey struct{}
+
+// ContextWithSpan returns ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanCtxKey{}, span)
+}
+
+// SpanFromContext returns span carried by ctx, nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,167 @@
+// Package tracing starts a server span for each request served by mux.Router, spans follow OpenTelemetry conventions
+// and W3C Trace Context is propagated, without depending on OpenTelemetry, ended spans are handed to an Exporter,
+// which may forward them to an OpenTelemetry SDK or collector, or keep them in memory for tests.
+//
+//	tracer := tracing.New(exporter)
+//	router.UseRouteAware(tracer.Middleware)
+//
+// Spans are named by method and route template, e.g. "GET /users/{id}", and params are recorded as attributes.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mfantcy/rdx-router/mux"
+)
+
+// Attribute keys of server spans, see OpenTelemetry semantic conventions of HTTP
+const (
+	AttributeMethod      = "http.request.method"
+	AttributeRoute       = "http.route"
+	AttributeStatusCode  = "http.response.status_code"
+	AttributePath        = "url.path"
+	AttributeServer      = "server.address"
+	AttributeClient      = "client.address"
+	AttributeUserAgent   = "user_agent.original"
+	AttributeParamPrefix = "http.route.param."
+)
+
+// Exporter receives ended spans which are sampled, it must be safe for concurrent use
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+// InMemoryExporter keeps exported spans, it is meant for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns exported spans in the order they ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Tracer starts spans, spans without parent are sampled, others are sampled as their parent
+type Tracer struct {
+	Exporter Exporter
+}
+
+func New(exporter Exporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+// Start starts span as child of span carried by ctx, it returns ctx carrying the new span
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.SpanContext()
+	}
+	span := t.start(parent, name, kind)
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) start(parent SpanContext, name string, kind SpanKind) *Span {
+	sc := SpanContext{TraceFlags: FlagsSampled}
+	if parent.IsValid() {
+		sc.TraceID, sc.TraceFlags, sc.TraceState = parent.TraceID, parent.TraceFlags, parent.TraceState
+	} else {
+		parent = SpanContext{}
+		for !sc.TraceID.IsValid() {
+			rand.Read(sc.TraceID[:])
+		}
+	}
+	for !sc.SpanID.IsValid() {
+		rand.Read(sc.SpanID[:])
+	}
+	return &Span{
+		data:   SpanData{Name: name, Kind: kind, SpanContext: sc, Parent: parent, StartTime: time.Now()},
+		tracer: t,
+	}
+}
+
+// Middleware is a mux.MiddlewareFunc starting server span of request, parent is taken from "traceparent" header,
+// it is meant for Router.UseRouteAware, so that span is named once route is matched.
+// Span of handler which panics is ended with error status, panic is left to Router.PanicFunc or http.Server
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		parent, _ := Extract(req.Header)
+		span := t.start(parent, "", SpanKindServer)
+		startServerSpan(span, req)
+		recorder, rw := mux.NewResponseRecorder(w)
+		served := false
+		defer func() {
+			statusCode := recorder.Status()
+			if !served {
+				span.AddEvent("exception", Attribute{Key: "exception.type", Value: "panic"})
+				span.SetStatus(StatusError, "handler panicked")
+				if statusCode == 0 { //response is left to recovery
+					statusCode = http.StatusInternalServerError
+				}
+			}
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			endServerSpan(span, statusCode)
+		}()
+		next.ServeHTTP(rw, req.WithContext(ContextWithSpan(req.Context(), span)))
+		served = true
+	})
+}
+
+// startServerSpan names span by method and route matched by req, and records attributes of req
+func startServerSpan(span *Span, req *http.Request) {
+	method := mux.NormalizeMethod(req.Method, "HTTP")
+	span.SetName(method)
+	span.SetAttributes(
+		Attribute{Key: AttributeMethod, Value: req.Method},
+		Attribute{Key: AttributePath, Value: req.URL.Path},
+		Attribute{Key: AttributeServer, Value: req.Host},
+		Attribute{Key: AttributeClient, Value: req.RemoteAddr},
+	)
+	if ua := req.UserAgent(); ua != "" {
+		span.SetAttributes(Attribute{Key: AttributeUserAgent, Value: ua})
+	}
+	if matched := mux.CurrentRoute(req); matched != nil {
+		span.SetName(method + " " + matched.Pattern)
+		span.SetAttributes(Attribute{Key: AttributeRoute, Value: matched.Pattern})
+	}
+	params := mux.RequestParams(req)
+	for i := 0; i < params.Count(); i++ {
+		if name := params.Name(i); name != "" {
+			span.SetAttributes(Attribute{Key: AttributeParamPrefix + name, Value: params.Value(i)})
+		}
+	}
+}
+
+// endServerSpan records status code of response and ends span, server errors are span errors
+func endServerSpan(span *Span, statusCode int) {
+	span.SetAttributes(Attribute{Key: AttributeStatusCode, Value: statusCode})
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, strconv.Itoa(statusCode)+" "+http.StatusText(statusCode))
+	}
+	span.End()
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mfantcy/rdx-router/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer_Middleware(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := New(exporter)
+	var handlerSpan *Span
+	r := mux.NewRouter()
+	r.GET("/users/{id:int}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handlerSpan = SpanFromContext(req.Context())
+		w.Write([]byte("user"))
+	}))
+	r.POST("/fail", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	r.UseRouteAware(tracer.Middleware)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	spans := exporter.Spans()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/{id:int}", span.Name)
+	assert.Equal(t, SpanKindServer, span.Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID.String())
+	assert.True(t, span.Parent.Remote)
+	assert.Equal(t, "vendor=value", span.SpanContext.TraceState)
+	assert.Equal(t, span.SpanContext, handlerSpan.SpanContext())
+	assert.Equal(t, StatusUnset, span.Status)
+	for key, value := range map[string]interface{}{
+		AttributeMethod:             "GET",
+		AttributeRoute:              "/users/{id:int}",
+		AttributePath:               "/users/42",
+		AttributeParamPrefix + "id": "42",
+		AttributeStatusCode:         200,
+	} {
+		actual, ok := span.Attribute(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, value, actual, key)
+	}
+
+	exporter.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	spans = exporter.Spans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "POST /fail", spans[0].Name)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.True(t, spans[0].SpanContext.IsSampled())
+	assert.Equal(t, StatusError, spans[0].Status)
+	assert.Equal(t, "502 Bad Gateway", spans[0].StatusDescription)
+	assert.Equal(t, "GET", spans[1].Name)
+	code, _ := spans[1].Attribute(AttributeStatusCode)
+	assert.Equal(t, 404, code)
+	assert.NotEqual(t, spans[0].SpanContext.TraceID, spans[1].SpanContext.TraceID)
+}
+
+func TestTracer_MiddlewarePanic(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	r := mux.NewRouter()
+	var recovered interface{}
+	r.PanicFunc = func(rev interface{}) http.HandlerFunc {
+		recovered = rev
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	r.GET("/panic", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	r.UseRouteAware(New(exporter).Middleware)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, "boom", recovered)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	spans := exporter.Spans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /panic", spans[0].Name)
+	assert.Equal(t, StatusError, spans[0].Status)
+	assert.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+	code, _ := spans[0].Attribute(AttributeStatusCode)
+	assert.Equal(t, 500, code)
+}
+
+func TestTracer_Start(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := New(exporter)
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindInternal)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetStatus(StatusOK, "ignored")
+	child.SetStatus(StatusError, "too late")
+	child.End()
+	child.End()
+	parent.End()
+	spans := exporter.Spans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, StatusOK, spans[0].Status)
+	assert.Empty(t, spans[0].StatusDescription)
+	assert.Equal(t, parent.SpanContext(), spans[0].Parent)
+	assert.Equal(t, parent.SpanContext().TraceID, spans[0].SpanContext.TraceID)
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Equal(t, parent.SpanContext().Traceparent(), header.Get("traceparent"))
+	sc, ok := Extract(header)
+	assert.True(t, ok)
+	assert.Equal(t, parent.SpanContext().SpanID, sc.SpanID)
+}
+
+func TestTracer_NotSampled(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	r := mux.NewRouter()
+	r.GET("/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	r.UseRouteAware(New(exporter).Middleware)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, exporter.Spans())
+}
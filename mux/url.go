@@ -0,0 +1,179 @@
+package mux
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
+
+type patternSegment struct {
+	static   string
+	param    bool
+	name     string
+	regexp   string
+	catchAll bool
+	//match checks values of placeholder with regexp, it is set by newURLPattern
+	match tree.MatchFunc
+}
+
+// urlPattern is a route pattern prepared for building paths, regexps of placeholders are compiled once
+type urlPattern struct {
+	pattern  string
+	params   []string
+	segments []patternSegment
+}
+
+// newURLPattern parses pattern of a registered route, it is already validated by tree
+func newURLPattern(pattern string) *urlPattern {
+	segments, _ := parsePattern(pattern)
+	p := &urlPattern{pattern: pattern, segments: segments}
+	for i := range p.segments {
+		segment := &p.segments[i]
+		if !segment.param {
+			continue
+		}
+		p.params = append(p.params, segment.name)
+		if segment.regexp == "" {
+			continue
+		}
+		if segment.match = tree.Matcher(segment.regexp); segment.match == nil {
+			if rx, err := regexp.Compile("^(?:" + segment.regexp + ")$"); err == nil {
+				segment.match = rx.MatchString
+			}
+		}
+	}
+	return p
+}
+
+// parsePattern splits a route pattern into static parts and placeholders, placeholders are parsed by tree
+func parsePattern(pattern string) (segments []patternSegment, err error) {
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			continue
+		}
+		if i > start {
+			segments = append(segments, patternSegment{static: pattern[start:i]})
+		}
+		end := tree.PlaceholderEnd(pattern, i)
+		if end == len(pattern) {
+			return nil, &tree.PatternError{Err: tree.ErrInvalidPlaceholder, Detail: "\"{\" is not closed"}
+		}
+		p, err := tree.ParsePlaceholder(pattern[i+1 : end])
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, patternSegment{param: true, name: p.Name, regexp: p.Regexp, catchAll: p.CatchAll})
+		i, start = end, end+1
+	}
+	if start < len(pattern) {
+		segments = append(segments, patternSegment{static: pattern[start:]})
+	}
+	return
+}
+
+// validate checks value of placeholder, "/" is escaped in values of routes matched by escaped path
+func (s patternSegment) validate(value string, rawPath bool) error {
+	if value == "" {
+		return errors.New("param '" + s.name + "' is empty")
+	}
+	if !s.catchAll && !rawPath && strings.IndexByte(value, '/') >= 0 {
+		return errors.New("param '" + s.name + "' value '" + value + "' must not contain \"/\"")
+	}
+	if s.match != nil && !s.match(value) {
+		return errors.New("param '" + s.name + "' value '" + value + "' does not match '" + s.regexp + "'")
+	}
+	return nil
+}
+
+func (s patternSegment) escape(value string) string {
+	if !s.catchAll {
+		return url.PathEscape(value)
+	}
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// build fills placeholders of pattern with values
+func (p *urlPattern) build(values map[string]string, rawPath bool) (string, error) {
+	var path []byte
+	for _, segment := range p.segments {
+		if !segment.param {
+			path = append(path, segment.static...)
+			continue
+		}
+		if segment.name == "" {
+			return "", errors.New("unnamed placeholder in '" + p.pattern + "' can not be built")
+		}
+		value, ok := values[segment.name]
+		if !ok {
+			return "", errors.New("param '" + segment.name + "' of '" + p.pattern + "' is missing")
+		}
+		if err := segment.validate(value, rawPath); err != nil {
+			return "", err
+		}
+		path = append(path, segment.escape(value)...)
+	}
+	return string(path), nil
+}
+
+// URL builds path of route registered with name, params are pairs of param name and value,
+// optional parts of route are left out if their params are not given, params not in the path built are an error
+func (r *Router) URL(name string, params ...string) (string, error) {
+	methodCtx, ok := r.current().names[name]
+	if !ok {
+		return "", errors.New("route name '" + name + "' not found")
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New("params must be pairs of name and value")
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+	variant, err := variantOf(methodCtx.urlPatterns, values)
+	if err != nil {
+		return "", err
+	}
+	return variant.build(values, r.UseRawPath)
+}
+
+// variantOf returns the variant of which named params are exactly the params given, the first variant if none is,
+// so that missing params are reported by build, params used by no variant are an error
+func variantOf(variants []*urlPattern, values map[string]string) (*urlPattern, error) {
+	for _, variant := range variants {
+		used := 0
+		for _, param := range variant.params {
+			if _, ok := values[param]; ok && param != "" {
+				used++
+			} else if param != "" {
+				used = -1
+				break
+			}
+		}
+		if used == len(values) {
+			return variant, nil
+		}
+	}
+	for name := range values {
+		if !variants[0].hasParam(name) {
+			return nil, errors.New("param '" + name + "' is not a param of '" + variants[0].pattern + "'")
+		}
+	}
+	return variants[0], nil
+}
+
+func (p *urlPattern) hasParam(name string) bool {
+	for _, param := range p.params {
+		if param == name && param != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfantcy/rdx-router/tree"
+)
+
+func TestParsePattern(t *testing.T) {
+	segments, err := parsePattern("/users/{id:[0-9]{1,3}}/{}/files/{path...}")
+	assert.NoError(t, err)
+	assert.Equal(t, []patternSegment{
+		{static: "/users/"},
+		{param: true, name: "id", regexp: "[0-9]{1,3}"},
+		{static: "/"},
+		{param: true},
+		{static: "/files/"},
+		{param: true, name: "path", catchAll: true},
+	}, segments)
+	segments, _ = parsePattern("{*rest}")
+	assert.Equal(t, []patternSegment{{param: true, name: "rest", catchAll: true}}, segments)
+	segments, _ = parsePattern("{*}")
+	assert.Equal(t, []patternSegment{{param: true}}, segments)
+	_, err = parsePattern("/users/{id")
+	assert.True(t, errors.Is(err, tree.ErrInvalidPlaceholder))
+	_, err = parsePattern("/users/{id!}")
+	assert.True(t, errors.Is(err, tree.ErrInvalidParamName))
+}
+
+func TestNewURLPattern(t *testing.T) {
+	p := newURLPattern("/users/{id:int}/{slug:[a-z]+}/{}/{path...}")
+	assert.Equal(t, []string{"id", "slug", "", "path"}, p.params)
+	assert.NotNil(t, p.segments[1].match)
+	assert.NotNil(t, p.segments[3].match)
+	assert.Nil(t, p.segments[5].match)
+	assert.True(t, p.segments[3].match("abc"))
+	assert.False(t, p.segments[3].match("abc1"))
+
+	_, err := p.build(map[string]string{"id": "x", "slug": "a"}, false)
+	assert.EqualError(t, err, "param 'id' value 'x' does not match 'int'")
+}
+
+func TestRouter_URL(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/{id:[0-9]+}", nopHandler).Name("user")
+	r.GET("/users/{id:[0-9]+}/posts/{slug}", nopHandler).Name("post")
+	r.GET("/static/{path...}", nopHandler).Name("static")
+	r.GET("/about", nopHandler).Name("about")
+	r.GET("/any/{}", nopHandler).Name("unnamed")
+	entry := r.GET("/late/{x}", nopHandler)
+	r.Group("/api", func(api RouteRegistrar) {
+		api.GET("/items/{item}", nopHandler).Name("api.item")
+	})
+	entry.Name("late")
+
+	u, err := r.URL("user", "id", "42")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/42", u)
+
+	u, err = r.URL("post", "id", "1", "slug", "hello world")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/1/posts/hello%20world", u)
+
+	u, err = r.URL("static", "path", "css/a b.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "/static/css/a%20b.css", u)
+
+	u, err = r.URL("about")
+	assert.NoError(t, err)
+	assert.Equal(t, "/about", u)
+
+	u, err = r.URL("api.item", "item", "7")
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/items/7", u)
+
+	u, err = r.URL("late", "x", "y")
+	assert.NoError(t, err)
+	assert.Equal(t, "/late/y", u)
+
+	_, err = r.URL("user", "id", "abc")
+	assert.Error(t, err)
+	_, err = r.URL("user")
+	assert.Error(t, err)
+	_, err = r.URL("user", "id")
+	assert.Error(t, err)
+	_, err = r.URL("post", "id", "1", "slug", "a/b")
+	assert.Error(t, err)
+	_, err = r.URL("unnamed")
+	assert.Error(t, err)
+	_, err = r.URL("not_exist")
+	assert.Error(t, err)
+}
+
+func TestRouter_NameDuplicateShouldPanic(t *testing.T) {
+	r := NewRouter()
+	r.GET("/a", nopHandler).Name("a")
+	r.POST("/a", nopHandler).Name("a")
+	assert.Panics(t, func() { r.GET("/b", nopHandler).Name("a") })
+}
+
+func TestRouter_URLWithTypedPlaceholder(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/{id:int}/{slug:alpha}", nopHandler).Name("user")
+
+	u, err := r.URL("user", "id", "-5", "slug", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/-5/bob", u)
+	_, err = r.URL("user", "id", "x", "slug", "bob")
+	assert.Error(t, err)
+	_, err = r.URL("user", "id", "1", "slug", "b0b")
+	assert.Error(t, err)
+}
+
+func TestRouter_URLWithSegmentPlaceholders(t *testing.T) {
+	var name, version string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name, version = RequestParams(req).ValueOf("name"), RequestParams(req).ValueOf("version")
+	})
+	r := NewRouter()
+	r.GET("/v{version:int}/files/{name}.json", handler).Name("file")
+
+	u, err := r.URL("file", "version", "2", "name", "a b")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v2/files/a%20b.json", u)
+	_, err = r.URL("file", "version", "x", "name", "a")
+	assert.Error(t, err)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v3/files/report.json", nil))
+	assert.Equal(t, "report", name)
+	assert.Equal(t, "3", version)
+}
+
+func TestRouter_URLShouldRejectParamsNotUsed(t *testing.T) {
+	r := NewRouter()
+	r.GET("/o/{x}[/{y}[/{z}]]", nopHandler).Name("opt")
+	r.GET("/about", nopHandler).Name("about")
+
+	u, err := r.URL("opt", "x", "1", "y", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "/o/1/2", u)
+	_, err = r.URL("opt", "x", "1", "z", "3")
+	assert.EqualError(t, err, "param 'y' of '/o/{x}/{y}/{z}' is missing")
+	_, err = r.URL("opt", "x", "1", "w", "3")
+	assert.EqualError(t, err, "param 'w' is not a param of '/o/{x}/{y}/{z}'")
+	_, err = r.URL("about", "x", "1")
+	assert.EqualError(t, err, "param 'x' is not a param of '/about'")
+}
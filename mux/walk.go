@@ -0,0 +1,59 @@
+package mux
+
+import "github.com/mfantcy/rdx-router/tree"
+
+type RouteInfo struct {
+	Host    string
+	Pattern string
+	Methods []string
+	Params  []string
+	Name    string
+}
+
+type WalkFunc func(route RouteInfo) error
+
+// Walk calls walkFunc for each route of served table, routes not bound to any host come first,
+// walking stops at the first error returned by walkFunc
+func (r *Router) Walk(walkFunc WalkFunc) error {
+	t := r.current()
+	if err := walkRoutes(t.tree, "", nil, walkFunc); err != nil {
+		return err
+	}
+	for _, h := range t.hosts {
+		if err := walkRoutes(h.tree, h.pattern, h.params, walkFunc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns all registered routes in the order of Walk
+func (r *Router) Routes() (routes []RouteInfo) {
+	r.Walk(func(route RouteInfo) error {
+		routes = append(routes, route)
+		return nil
+	})
+	return
+}
+
+func walkRoutes(trie tree.TrieInterface, host string, hostParams []string, walkFunc WalkFunc) error {
+	return trie.Walk(func(node tree.NodeInterface) error {
+		route, ok := node.Context().(*Route)
+		if !ok {
+			return nil
+		}
+		info := RouteInfo{
+			Host:    host,
+			Pattern: node.FullPathPattern(),
+			Methods: route.Methods(),
+			Params:  append(append([]string(nil), hostParams...), node.Params()...),
+		}
+		for _, method := range info.Methods {
+			if name := route.methods[method].name; name != "" {
+				info.Name = name
+				break
+			}
+		}
+		return walkFunc(info)
+	})
+}
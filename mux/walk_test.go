@@ -0,0 +1,38 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Walk(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	r := NewRouter()
+	r.Handle("/users/{id:[0-9]+}", handler, "PUT", "GET").Name("user")
+	r.GET("/", handler)
+	r.Group("/static", func(static RouteRegistrar) {
+		static.GET("/{path...}", handler).Name("static")
+	})
+	r.Host("{tenant}.example.com", func(host RouteRegistrar) {
+		host.POST("/items/{item}", handler)
+	})
+
+	assert.Equal(t, []RouteInfo{
+		{Pattern: "/", Methods: []string{"GET"}},
+		{Pattern: "/static/{path...}", Methods: []string{"GET"}, Params: []string{"path"}, Name: "static"},
+		{Pattern: "/users/{id:[0-9]+}", Methods: []string{"GET", "PUT"}, Params: []string{"id"}, Name: "user"},
+		{Host: "{tenant}.example.com", Pattern: "/items/{item}", Methods: []string{"POST"}, Params: []string{"tenant", "item"}},
+	}, r.Routes())
+
+	stop := errors.New("stop")
+	count := 0
+	err := r.Walk(func(route RouteInfo) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+}
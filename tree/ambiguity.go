@@ -0,0 +1,223 @@
+package tree
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxOverlapStates bounds the states explored by regexpContains, patterns needing more are not reported ambiguous
+const maxOverlapStates = 4096
+
+// matcherRegexps are regexps equivalent to built-in placeholder types, they are used to tell ambiguous patterns only,
+// types added by RegisterMatcher are only ambiguous with themselves
+var matcherRegexps = map[string]string{
+	"int":   "-?[0-9]+",
+	"uuid":  "[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}",
+	"alpha": "[a-zA-Z]+",
+}
+
+// constraintRegexp returns regexp matching path segments matched by regexp, matcher or segment node of pattern,
+// ok is false if it is not known, i.e. for placeholder types added by RegisterMatcher
+func constraintRegexp(nodeType nodeType, pattern string) (expr string, ok bool) {
+	switch nodeType {
+	case nodeTypeRegexp:
+		return pattern, true
+	case nodeTypeMatcher:
+		expr, ok = matcherRegexps[pattern]
+		return
+	case nodeTypeSegment:
+		parts, _, err := parseSegment(pattern, 0)
+		if err != nil {
+			return "", false
+		}
+		for _, part := range parts {
+			switch {
+			case !part.param:
+				expr += regexp.QuoteMeta(part.static)
+			case part.regexp == "":
+				expr += "[^/]+"
+			case Matcher(part.regexp) != nil:
+				sub, known := matcherRegexps[part.regexp]
+				if !known {
+					return "", false
+				}
+				expr += "(?:" + sub + ")"
+			default:
+				expr += "(?:" + part.regexp + ")"
+			}
+		}
+		return expr, true
+	}
+	return "", false
+}
+
+// overlaps reports whether one of regexp, matcher or segment patterns matches every path segment the other matches,
+// the one tried later by lookUp may then be shadowed by the other
+func overlaps(aType nodeType, a string, bType nodeType, b string) bool {
+	if aType == bType && a == b {
+		return true
+	}
+	ra, ok := constraintRegexp(aType, a)
+	if !ok {
+		return false
+	}
+	rb, ok := constraintRegexp(bType, b)
+	if !ok {
+		return false
+	}
+	return regexpContains(ra, rb) || regexpContains(rb, ra)
+}
+
+// regexpContains reports whether every path segment matched by inner is matched by outer, both match whole segments,
+// e.g. "-?[0-9]+" contains "[0-9]+" and "\d+" contains "[0-9]+". Assertions like "^" and "\b" are taken as matched
+func regexpContains(outer, inner string) bool {
+	po, err := compileProg(outer)
+	if err != nil {
+		return false
+	}
+	pi, err := compileProg(inner)
+	if err != nil {
+		return false
+	}
+	runes := representativeRunes(po, pi)
+	type state struct{ outer, inner []uint32 }
+	start := state{closure(po, []uint32{uint32(po.Start)}), closure(pi, []uint32{uint32(pi.Start)})}
+	seen := map[string]bool{stateKey(start.outer, start.inner): true}
+	queue := []state{start}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		if matches(pi, s.inner) && !matches(po, s.outer) {
+			return false
+		}
+		for _, r := range runes {
+			next := state{step(po, s.outer, r), step(pi, s.inner, r)}
+			if len(next.inner) == 0 {
+				continue
+			}
+			key := stateKey(next.outer, next.inner)
+			if seen[key] {
+				continue
+			}
+			if len(seen) >= maxOverlapStates {
+				return false
+			}
+			seen[key] = true
+			queue = append(queue, next)
+		}
+	}
+	return true
+}
+
+func compileProg(expr string) (*syntax.Prog, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return syntax.Compile(re.Simplify())
+}
+
+// representativeRunes returns one rune of each range of runes both programs treat alike, "/" is left out
+func representativeRunes(progs ...*syntax.Prog) []rune {
+	bounds := map[rune]bool{0: true, '/': true, '/' + 1: true, '\n': true, '\n' + 1: true}
+	for _, prog := range progs {
+		for _, inst := range prog.Inst {
+			if inst.Op != syntax.InstRune && inst.Op != syntax.InstRune1 {
+				continue
+			}
+			for i, r := range inst.Rune {
+				if i%2 == 0 || len(inst.Rune) == 1 {
+					bounds[r] = true
+				}
+				if i%2 == 1 || len(inst.Rune) == 1 {
+					bounds[r+1] = true
+				}
+				if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+					for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+						bounds[f], bounds[f+1] = true, true
+					}
+				}
+			}
+		}
+	}
+	runes := make([]rune, 0, len(bounds))
+	for r := range bounds {
+		if r != '/' && r <= unicode.MaxRune {
+			runes = append(runes, r)
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// closure returns instructions consuming a rune or matching reached from pcs without consuming input
+func closure(prog *syntax.Prog, pcs []uint32) []uint32 {
+	var out []uint32
+	visited := make(map[uint32]bool)
+	stack := append([]uint32(nil), pcs...)
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[pc] {
+			continue
+		}
+		visited[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Out, inst.Arg)
+		case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+			stack = append(stack, inst.Out)
+		case syntax.InstMatch, syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			out = append(out, pc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func step(prog *syntax.Prog, pcs []uint32, r rune) []uint32 {
+	var next []uint32
+	for _, pc := range pcs {
+		inst := &prog.Inst[pc]
+		matched := false
+		switch inst.Op {
+		case syntax.InstRune:
+			matched = inst.MatchRune(r)
+		case syntax.InstRune1:
+			matched = inst.Rune[0] == r
+		case syntax.InstRuneAny:
+			matched = true
+		case syntax.InstRuneAnyNotNL:
+			matched = r != '\n'
+		}
+		if matched {
+			next = append(next, inst.Out)
+		}
+	}
+	return closure(prog, next)
+}
+
+func matches(prog *syntax.Prog, pcs []uint32) bool {
+	for _, pc := range pcs {
+		if prog.Inst[pc].Op == syntax.InstMatch {
+			return true
+		}
+	}
+	return false
+}
+
+func stateKey(outer, inner []uint32) string {
+	var b strings.Builder
+	for _, pc := range outer {
+		b.WriteRune(rune(pc) + 1)
+	}
+	b.WriteByte(0)
+	for _, pc := range inner {
+		b.WriteRune(rune(pc) + 1)
+	}
+	return b.String()
+}
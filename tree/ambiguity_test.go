@@ -0,0 +1,32 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexpContains(t *testing.T) {
+	assert.True(t, regexpContains("[0-9]+", "\\d+"))
+	assert.True(t, regexpContains("-?[0-9]+", "[0-9]+"))
+	assert.False(t, regexpContains("[0-9]+", "-?[0-9]+"))
+	assert.True(t, regexpContains(".+", "[a-z]{2,}"))
+	assert.True(t, regexpContains("[^/]+-[^/]+", "(?:[0-9]{4})-(?:[0-9]{2})"))
+	assert.False(t, regexpContains("[0-5]+", "[3-9]+"))
+	assert.False(t, regexpContains("[a-z]+", "[0-9]+"))
+	assert.True(t, regexpContains("(?i)abc", "ABC"))
+	assert.False(t, regexpContains("abc", "(?i)abc"))
+	assert.True(t, regexpContains("[^x]+", "[a-w]+"), "\"/\" is never in a segment")
+	assert.False(t, regexpContains("[0-9", "[0-9]+"))
+}
+
+func TestOverlaps(t *testing.T) {
+	assert.True(t, overlaps(nodeTypeRegexp, "[0-9]+", nodeTypeMatcher, "int"))
+	assert.True(t, overlaps(nodeTypeMatcher, "int", nodeTypeRegexp, "[0-9]+"))
+	assert.False(t, overlaps(nodeTypeMatcher, "int", nodeTypeMatcher, "alpha"))
+	assert.True(t, overlaps(nodeTypeSegment, "{}-{}", nodeTypeSegment, "{:[0-9]{4}}-{:[0-9]{2}}"))
+	assert.True(t, overlaps(nodeTypeSegment, "v{:int}", nodeTypeRegexp, "v[0-9]+"))
+	assert.False(t, overlaps(nodeTypeSegment, "{}.json", nodeTypeSegment, "{}.xml"))
+	assert.True(t, overlaps(nodeTypeMatcher, "custom", nodeTypeMatcher, "custom"))
+	assert.False(t, overlaps(nodeTypeMatcher, "custom", nodeTypeRegexp, ".+"))
+}
@@ -0,0 +1,106 @@
+package tree
+
+// FindCaseInsensitivePath returns path as registered for path of which static parts differ in case of ASCII letters,
+// params are kept as they are, a registered path differing in trailing slash is returned if fixTrailingSlash,
+// it is meant for requests not found by Lookup, as it is slower
+func (n *node) FindCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool) {
+	if buf, ok := n.findCaseInsensitive(path, make([]byte, 0, len(path)+1)); ok {
+		return string(buf), true
+	}
+	if fixTrailingSlash && len(path) > 1 {
+		if path[len(path)-1] == '/' {
+			path = path[:len(path)-1]
+		} else {
+			path += "/"
+		}
+		if buf, ok := n.findCaseInsensitive(path, make([]byte, 0, len(path))); ok {
+			return string(buf), true
+		}
+	}
+	return "", false
+}
+
+// findCaseInsensitive appends registered path of static node n and its branches to buf
+func (n *node) findCaseInsensitive(path string, buf []byte) ([]byte, bool) {
+	if len(path) < n.pathLen || !equalFoldASCII(path[:n.pathLen], n.path) {
+		return buf, false
+	}
+	return n.findTailCaseInsensitive(path[n.pathLen:], append(buf, n.path...))
+}
+
+// findTailCaseInsensitive tries branches of n in the same order as lookUp does
+func (n *node) findTailCaseInsensitive(path string, buf []byte) ([]byte, bool) {
+	if path == "" {
+		return buf, n.leaf != nil
+	}
+	lower, upper := path[0], path[0]
+	if 'A' <= lower && lower <= 'Z' {
+		lower += 'a' - 'A'
+	} else if 'a' <= upper && upper <= 'z' {
+		upper -= 'a' - 'A'
+	}
+	for _, c := range [2]byte{lower, upper} {
+		if child := n.staticBranches[c]; child != nil {
+			if found, ok := child.findCaseInsensitive(path, buf); ok {
+				return found, true
+			}
+		}
+		if lower == upper {
+			break
+		}
+	}
+	if !n.hasNonStatic {
+		return buf, false
+	}
+	end := 0
+	for end < len(path) && path[end] != '/' {
+		end++
+	}
+	if end == 0 {
+		return buf, false
+	}
+	for _, child := range n.regexpBranches {
+		value, ok := path[:end], false
+		if child.segment != nil {
+			value, ok = child.segment.canonical(value)
+		} else {
+			ok = child.match(value)
+		}
+		if ok {
+			if found, ok := child.findTailCaseInsensitive(path[end:], append(buf, value...)); ok {
+				return found, true
+			}
+		}
+	}
+	if n.wildBranch != nil {
+		if found, ok := n.wildBranch.findTailCaseInsensitive(path[end:], append(buf, path[:end]...)); ok {
+			return found, true
+		}
+	}
+	if n.catchAllBranch != nil && n.catchAllBranch.leaf != nil {
+		return append(buf, path...), true
+	}
+	return buf, false
+}
+
+func equalFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		ca, cb := a[i], b[i]
+		if ca == cb {
+			continue
+		}
+		if 'A' <= ca && ca <= 'Z' {
+			ca += 'a' - 'A'
+		}
+		if 'A' <= cb && cb <= 'Z' {
+			cb += 'a' - 'A'
+		}
+		if ca != cb {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCaseInsensitivePath(t *testing.T) {
+	tree := NewTree()
+	for _, pattern := range []string{"/Users", "/users/{id:int}/Posts", "/users/{name}/profile/", "/Files/{name}.JSON", "/static/{path...}", "/About/Team"} {
+		tree.Add(pattern, pattern)
+	}
+	cases := map[string]string{
+		"/users":              "/Users",
+		"/USERS":              "/Users",
+		"/users/12/posts":     "/users/12/Posts",
+		"/USERS/Bob/PROFILE/": "/users/Bob/profile/",
+		"/files/Report.json":  "/Files/Report.JSON",
+		"/STATIC/A/b.CSS":     "/static/A/b.CSS",
+		"/about/team":         "/About/Team",
+	}
+	for path, expected := range cases {
+		fixed, ok := tree.FindCaseInsensitivePath(path, false)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, fixed, path)
+	}
+	for _, path := range []string{"/users/", "/users/x/posts", "/about", "/about/teams", "/USERS/Bob/PROFILE", "/static/"} {
+		_, ok := tree.FindCaseInsensitivePath(path, false)
+		assert.False(t, ok, path)
+	}
+
+	fixed, ok := tree.FindCaseInsensitivePath("/users/bob/profile", true)
+	assert.True(t, ok)
+	assert.Equal(t, "/users/bob/profile/", fixed)
+	fixed, ok = tree.FindCaseInsensitivePath("/about/TEAM/", true)
+	assert.True(t, ok)
+	assert.Equal(t, "/About/Team", fixed)
+	assert.True(t, equalFoldASCII("aZ-09", "Az-09"))
+	assert.False(t, equalFoldASCII("[", "{"))
+}
@@ -9,12 +9,24 @@ type NodeInterface interface {
 	FullPathPattern() string
 	Context() interface{}
 	Params() []string
+	ParamTypes() []string
 }
 
 type AddHookFunc func(context interface{}) interface{}
 
+type WalkFunc func(node NodeInterface) error
+
+type CopyFunc func(context interface{}) interface{}
+
 type TrieInterface interface {
-	Lookup(path string, fixTailingSlash bool) (interface{}, []*Pair, bool)
+	Lookup(path string, fixTailingSlash bool) (interface{}, []Pair, bool)
+	LookupAppend(path string, fixTailingSlash bool, pairs []Pair) (interface{}, []Pair, bool)
+	FindCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool)
 	Add(pattern string, ctx interface{}) NodeInterface
 	AddThen(pattern string, callback AddHookFunc) NodeInterface
+	TryAddThen(pattern string, callback AddHookFunc) (NodeInterface, error)
+	Find(pattern string) NodeInterface
+	Remove(pattern string) bool
+	Walk(walkFunc WalkFunc) error
+	Clone(copyContext CopyFunc) TrieInterface
 }
@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"strconv"
+	"sync"
+)
+
+// MatchFunc reports whether a path segment matches a placeholder
+type MatchFunc func(segment string) bool
+
+// ConvertFunc converts value of a placeholder type, e.g. "int" values to int
+type ConvertFunc func(segment string) (interface{}, error)
+
+var (
+	registryMu sync.RWMutex
+
+	// matchers are placeholder types, they are used by name in place of regexp, e.g. "/users/{id:int}"
+	matchers = map[string]MatchFunc{
+		"int":   isInt,
+		"uuid":  isUUID,
+		"alpha": isAlpha,
+	}
+
+	converters = map[string]ConvertFunc{
+		"int":  convertInt,
+		"uuid": convertUUID,
+	}
+)
+
+// RegisterMatcher adds placeholder type name matched by match, e.g. "/pkg/{version:semver}",
+// matchers must be registered before patterns using them are added, name is no longer read as regexp then.
+// It panics if name is not made of letters, digits and '_' or is already registered
+func RegisterMatcher(name string, match MatchFunc) {
+	if !isMatcherName(name) {
+		panic("tree: invalid matcher name '" + name + "'")
+	}
+	if match == nil {
+		panic("tree: nil matcher '" + name + "'")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := matchers[name]; ok {
+		panic("tree: matcher '" + name + "' is already registered")
+	}
+	matchers[name] = match
+}
+
+// RegisterConverter sets convert of placeholder type name, values of types without converter stay strings.
+// Like matchers, converters must be registered before patterns using them are added, converters are resolved once
+// pattern is added. It panics if name is not a registered matcher or has a converter already
+func RegisterConverter(name string, convert ConvertFunc) {
+	if convert == nil {
+		panic("tree: nil converter '" + name + "'")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := matchers[name]; !ok {
+		panic("tree: converter of unknown matcher '" + name + "'")
+	}
+	if _, ok := converters[name]; ok {
+		panic("tree: converter '" + name + "' is already registered")
+	}
+	converters[name] = convert
+}
+
+// Matcher returns MatchFunc of placeholder type name, nil if name is not a placeholder type
+func Matcher(name string) MatchFunc {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return matchers[name]
+}
+
+// Converter returns ConvertFunc of placeholder type name, nil if the type has no converter
+func Converter(name string) ConvertFunc {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return converters[name]
+}
+
+func isMatcherName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; !(c == '_' || '0' <= c && c <= '9' || 'a' <= c|0x20 && c|0x20 <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+func convertInt(s string) (interface{}, error) {
+	return strconv.Atoi(s)
+}
+
+// isInt matches "-?[0-9]+"
+func isInt(s string) bool {
+	if len(s) > 0 && s[0] == '-' {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID matches canonical form "[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}"
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if c != '-' {
+				return false
+			}
+		} else if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlpha matches "[a-zA-Z]+"
+func isAlpha(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
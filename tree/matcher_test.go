@@ -0,0 +1,179 @@
+package tree
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchers(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"int":   {"0": true, "123": true, "-42": true, "": false, "-": false, "1a": false, "+1": false},
+		"uuid":  {"123e4567-e89b-12d3-a456-426614174000": true, "123E4567-E89B-12D3-A456-426614174000": true, "123e4567e89b12d3a456426614174000": false, "123e4567-e89b-12d3-a456-42661417400g": false, "": false},
+		"alpha": {"abc": true, "ABCxyz": true, "": false, "ab1": false, "a-b": false, "@": false, "[": false},
+	}
+	for name, values := range cases {
+		match := Matcher(name)
+		assert.NotNil(t, match, name)
+		for value, expected := range values {
+			assert.Equal(t, expected, match(value), name+" "+value)
+		}
+	}
+	assert.Nil(t, Matcher("[0-9]+"))
+}
+
+func TestLookupTypedAndAnchoredRegexp(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/users/{id:int}", "int")
+	tree.Add("/users/{id:uuid}", "uuid")
+	tree.Add("/users/{slug:alpha}", "alpha")
+	tree.Add("/users/{other}", "wild")
+	tree.Add("/words/{w:a|ab}", "regexp")
+
+	assertFoundParams(t, tree, "/users/-12", false, []*Pair{{"id", "-12"}}, "int")
+	assertFoundParams(t, tree, "/users/123e4567-e89b-12d3-a456-426614174000", false, []*Pair{{"id", "123e4567-e89b-12d3-a456-426614174000"}}, "uuid")
+	assertFoundParams(t, tree, "/users/bob", false, []*Pair{{"slug", "bob"}}, "alpha")
+	assertFoundParams(t, tree, "/users/bob1", false, []*Pair{{"other", "bob1"}}, "wild")
+	assertFound(t, tree, "/words/ab", false, "regexp")
+	assertNotFound(t, tree, "/words/abc", false)
+	assertNotFound(t, tree, "/words/xab", false)
+	assert.Equal(t, "/users/{id:int}", tree.Find("/users/{id:int}").FullPathPattern())
+}
+
+func isSemver(s string) bool {
+	parts := 0
+	for _, part := range strings.Split(s, ".") {
+		if !isInt(part) || part[0] == '-' {
+			return false
+		}
+		parts++
+	}
+	return parts == 3
+}
+
+func TestRegisterMatcher(t *testing.T) {
+	RegisterMatcher("test_semver", isSemver)
+	RegisterConverter("test_semver", func(s string) (interface{}, error) {
+		return strings.Split(s, "."), nil
+	})
+	assert.Panics(t, func() { RegisterMatcher("test_semver", isSemver) })
+	assert.Panics(t, func() { RegisterMatcher("a-b", isSemver) })
+	assert.Panics(t, func() { RegisterMatcher("", isSemver) })
+	assert.Panics(t, func() { RegisterConverter("test_unknown", convertInt) })
+	assert.Panics(t, func() { RegisterConverter("int", convertInt) })
+
+	tree := NewTree()
+	tree.Add("/pkg/{version:test_semver}", "semver")
+	tree.Add("/pkg/{version:int}", "int")
+	tree.Add("/pkg/{name}", "wild")
+	tree.Add("/pkg/{version:test_semver}/files", "files")
+
+	assertFoundParams(t, tree, "/pkg/1.20.3", false, []*Pair{{"version", "1.20.3"}}, "semver")
+	assertFoundParams(t, tree, "/pkg/12", false, []*Pair{{"version", "12"}}, "int")
+	assertFoundParams(t, tree, "/pkg/1.2", false, []*Pair{{"name", "1.2"}}, "wild")
+	assertFoundParams(t, tree, "/pkg/1.2.3/files", false, []*Pair{{"version", "1.2.3"}}, "files")
+	assert.Equal(t, "/pkg/{version:test_semver}", tree.Find("/pkg/{version:test_semver}").FullPathPattern())
+	assert.Equal(t, []string{"test_semver"}, tree.Find("/pkg/{version:test_semver}/files").ParamTypes())
+	assert.Equal(t, []string{""}, tree.Find("/pkg/{name}").ParamTypes())
+
+	convert := Converter("test_semver")
+	value, err := convert("1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, value)
+	assert.Nil(t, Converter("alpha"))
+	value, err = Converter("int")("-7")
+	assert.NoError(t, err)
+	assert.Equal(t, -7, value)
+	value, err = Converter("uuid")("123E4567-e89b-12d3-a456-426614174000")
+	assert.NoError(t, err)
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", value.(UUID).String())
+}
+
+func TestMatcherShouldBacktrack(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/v/{a:int}/x", "int")
+	tree.Add("/v/{a:alpha}/y", "alpha")
+	tree.Add("/v/{a:[0-9a-z]+}/z", "regexp")
+	tree.Add("/v/{a}/{b:int}/{c}", "wild")
+
+	assertFoundParams(t, tree, "/v/1/x", false, []*Pair{{"a", "1"}}, "int")
+	assertFoundParams(t, tree, "/v/abc/y", false, []*Pair{{"a", "abc"}}, "alpha")
+	assertFoundParams(t, tree, "/v/1/z", false, []*Pair{{"a", "1"}}, "regexp")
+	assertFoundParams(t, tree, "/v/1/2/3", false, []*Pair{{"a", "1"}, {"b", "2"}, {"c", "3"}}, "wild")
+	assertNotFound(t, tree, "/v/1/y", false)
+	assert.Equal(t, []string{"", "int", ""}, tree.Find("/v/{a}/{b:int}/{c}").ParamTypes())
+	assert.True(t, tree.Remove("/v/{a:int}/x"))
+	assertNotFound(t, tree, "/v/1/x", false)
+	assertFoundParams(t, tree, "/v/abc/y", false, []*Pair{{"a", "abc"}}, "alpha")
+}
+
+var benchmarkSegments = []string{"12345", "abc", "123e4567-e89b-12d3-a456-426614174000"}
+
+func BenchmarkMatchInt(b *testing.B) {
+	findAll := regexp.MustCompile("[0-9]+")
+	anchored := regexp.MustCompile("^(?:[0-9]+)$")
+	b.Run("FindAllString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := benchmarkSegments[i%len(benchmarkSegments)]
+			m := findAll.FindAllString(s, 1)
+			_ = len(m) > 0 && m[0] == s
+		}
+	})
+	b.Run("Anchored", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			anchored.MatchString(benchmarkSegments[i%len(benchmarkSegments)])
+		}
+	})
+	b.Run("Matcher", func(b *testing.B) {
+		b.ReportAllocs()
+		match := Matcher("int")
+		for i := 0; i < b.N; i++ {
+			match(benchmarkSegments[i%len(benchmarkSegments)])
+		}
+	})
+}
+
+func BenchmarkMatchUUID(b *testing.B) {
+	findAll := regexp.MustCompile("[a-f0-9-]{36}")
+	anchored := regexp.MustCompile("^(?:[a-f0-9-]{36})$")
+	b.Run("FindAllString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := benchmarkSegments[i%len(benchmarkSegments)]
+			m := findAll.FindAllString(s, 1)
+			_ = len(m) > 0 && m[0] == s
+		}
+	})
+	b.Run("Anchored", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			anchored.MatchString(benchmarkSegments[i%len(benchmarkSegments)])
+		}
+	})
+	b.Run("Matcher", func(b *testing.B) {
+		b.ReportAllocs()
+		match := Matcher("uuid")
+		for i := 0; i < b.N; i++ {
+			match(benchmarkSegments[i%len(benchmarkSegments)])
+		}
+	})
+}
+
+func BenchmarkLookupRegexpVersusMatcher(b *testing.B) {
+	for _, pattern := range []string{"/users/{id:[0-9]+}/posts", "/users/{id:int}/posts"} {
+		tree := NewTree()
+		tree.Add(pattern, 1)
+		tree.Add("/users/{name}/posts", 2)
+		b.Run(pattern, func(b *testing.B) {
+			pairs := make([]Pair, 0, 4)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tree.LookupAppend("/users/12345/posts", false, pairs)
+			}
+		})
+	}
+}
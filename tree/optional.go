@@ -0,0 +1,103 @@
+package tree
+
+// Variants expands optional parts of pattern, a part in brackets may be left out, e.g. "/posts[/{page:[0-9]+}]"
+// is added as "/posts/{page:[0-9]+}" and "/posts", so is "/posts/{page?:[0-9]+}".
+// Parts may be nested, the variant with all parts comes first and the one without any comes last.
+// Brackets outside placeholders are never static text, a literal "[" or "]" of path can only be matched by placeholder
+func Variants(pattern string) ([]string, error) {
+	expanded, err := expandOptional(optionalPlaceholders(pattern))
+	if err != nil {
+		return nil, err
+	}
+	variants := expanded[:0]
+	for _, variant := range expanded {
+		if variant == "" { //all of "/{page?}" left out
+			variant = "/"
+		}
+		if !contains(variants, variant) { //"[[/a]]" is left out twice
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalPlaceholders rewrites optional placeholders "{name?}" to optional parts "[/{name}]"
+func optionalPlaceholders(pattern string) string {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			continue
+		}
+		end := PlaceholderEnd(pattern, i)
+		if end == len(pattern) {
+			return pattern
+		}
+		nameEnd := i + 1
+		for nameEnd < end && pattern[nameEnd] != ':' {
+			nameEnd++
+		}
+		if pattern[nameEnd-1] != '?' {
+			i = end
+			continue
+		}
+		start := i
+		if i > 0 && pattern[i-1] == '/' {
+			start = i - 1
+		}
+		optional := "[" + pattern[start:i] + "{" + pattern[i+1:nameEnd-1] + pattern[nameEnd:end+1] + "]"
+		pattern = pattern[:start] + optional + pattern[end+1:]
+		i = start + len(optional) - 1
+	}
+	return pattern
+}
+
+func expandOptional(pattern string) ([]string, error) {
+	open, depth := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			i = PlaceholderEnd(pattern, i)
+		case '[':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case ']':
+			if depth--; depth < 0 {
+				return nil, &PatternError{Err: ErrInvalidOptional, Detail: "\"]\" is not opened"}
+			} else if depth > 0 {
+				continue
+			}
+			if i == open+1 {
+				return nil, &PatternError{Err: ErrInvalidOptional, Detail: "optional part is empty"}
+			}
+			parts, err := expandOptional(pattern[open+1 : i])
+			if err != nil {
+				return nil, err
+			}
+			rests, err := expandOptional(pattern[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			variants := make([]string, 0, (len(parts)+1)*len(rests))
+			for _, part := range append(parts, "") {
+				for _, rest := range rests {
+					variants = append(variants, pattern[:open]+part+rest)
+				}
+			}
+			return variants, nil
+		}
+	}
+	if depth > 0 {
+		return nil, &PatternError{Err: ErrInvalidOptional, Detail: "\"[\" is not closed"}
+	}
+	return []string{pattern}, nil
+}
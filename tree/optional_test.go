@@ -0,0 +1,63 @@
+package tree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariants(t *testing.T) {
+	cases := map[string][]string{
+		"/posts":                        {"/posts"},
+		"/posts[/{page:[0-9]+}]":        {"/posts/{page:[0-9]+}", "/posts"},
+		"/posts/{page?:[0-9]{1,3}}":     {"/posts/{page:[0-9]{1,3}}", "/posts"},
+		"/{page?}":                      {"/{page}", "/"},
+		"/v{major}[.{minor}]":           {"/v{major}.{minor}", "/v{major}"},
+		"/v{major}[.{minor?}]":          {"/v{major}.{minor}", "/v{major}.", "/v{major}"},
+		"/archive[/{year}[/{month}]]":   {"/archive/{year}/{month}", "/archive/{year}", "/archive"},
+		"/a[/b][/c]":                    {"/a/b/c", "/a/b", "/a/c", "/a"},
+		"/users/{id}/posts[/{post?}]/x": {"/users/{id}/posts/{post}/x", "/users/{id}/posts/x"},
+	}
+	for pattern, expected := range cases {
+		variants, err := Variants(pattern)
+		assert.NoError(t, err, pattern)
+		assert.Equal(t, expected, variants, pattern)
+	}
+	for _, pattern := range []string{"/a[/b", "/a]/b", "/a[]/b", "/a[[/b]"} {
+		_, err := Variants(pattern)
+		assert.True(t, errors.Is(err, ErrInvalidOptional), pattern)
+	}
+}
+
+func TestAddOptional(t *testing.T) {
+	tree := NewTree()
+	var called int
+	node, err := tree.TryAddThen("/posts/{page?:int}", func(context interface{}) interface{} {
+		called++
+		return "posts"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, called)
+	assert.Equal(t, "/posts/{page:int}", node.FullPathPattern())
+	tree.Add("/archive[/{year}[/{month}]]", "archive")
+
+	assertFoundParams(t, tree, "/posts/2", false, []*Pair{{"page", "2"}}, "posts")
+	assertFoundParams(t, tree, "/posts", false, []*Pair{}, "posts")
+	assertNotFound(t, tree, "/posts/x", false)
+	assertFoundParams(t, tree, "/archive/2020/01", false, []*Pair{{"year", "2020"}, {"month", "01"}}, "archive")
+	assertFoundParams(t, tree, "/archive/2020", false, []*Pair{{"year", "2020"}}, "archive")
+	assertFoundParams(t, tree, "/archive", false, []*Pair{}, "archive")
+
+	_, err = tree.TryAddThen("/archive[/{y}]", nil)
+	assert.True(t, errors.Is(err, ErrParamConflict))
+	assert.Equal(t, "/archive[/{y}]", err.(*PatternError).Pattern)
+	_, err = tree.TryAddThen("/posts[/{page}", nil)
+	assert.True(t, errors.Is(err, ErrInvalidOptional))
+
+	assert.Equal(t, node, tree.Find("/posts[/{page:int}]"))
+	assert.True(t, tree.Remove("/archive[/{year}[/{month}]]"))
+	assertNotFound(t, tree, "/archive", false)
+	assertNotFound(t, tree, "/archive/2020", false)
+	assertFound(t, tree, "/posts", false, "posts")
+}
@@ -0,0 +1,66 @@
+package tree
+
+import (
+	"regexp"
+	"strings"
+)
+
+var paramNameRegexp = regexp.MustCompile("^$|^[a-zA-Z0-9_]+(-*[a-zA-Z0-9_]+)*$")
+
+// Placeholder is a placeholder of pattern, e.g. "{id:[0-9]+}", "{id:int}" or "{path...}"
+type Placeholder struct {
+	//Name is empty for unnamed placeholders "{}", "{*}" and "{...}"
+	Name     string
+	Regexp   string
+	CatchAll bool
+}
+
+// ParsePlaceholder parses body of placeholder between braces, it returns ErrInvalidParamName if name is invalid
+// and ErrInvalidRegexp if regexp is empty, regexp itself is not compiled
+func ParsePlaceholder(body string) (p Placeholder, err error) {
+	if idx := strings.IndexByte(body, ':'); idx >= 0 {
+		body, p.Regexp = body[:idx], body[idx+1:]
+		if p.Regexp == "" {
+			return p, &PatternError{Err: ErrInvalidRegexp, Detail: "regexp pattern is empty"}
+		}
+	} else if strings.HasSuffix(body, "...") {
+		body, p.CatchAll = body[:len(body)-3], true
+	} else if len(body) > 1 && body[0] == '*' {
+		body, p.CatchAll = body[1:], true
+	}
+	if body == "*" {
+		body = ""
+	}
+	if !ValidParamName(body) {
+		return p, &PatternError{Err: ErrInvalidParamName, Detail: "\"" + body + "\""}
+	}
+	p.Name = body
+	return p, nil
+}
+
+// ValidParamName reports whether name is a valid name of placeholder, empty names are valid
+func ValidParamName(name string) bool {
+	return paramNameRegexp.MatchString(name)
+}
+
+// PlaceholderEnd returns position of "}" closing the placeholder opened at pos, braces of regexp are skipped,
+// len(pattern) if placeholder is not closed
+func PlaceholderEnd(pattern string, pos int) int {
+	stack, backSlashOpen := 0, false
+	for i := pos + 1; i < len(pattern); i++ {
+		switch {
+		case backSlashOpen:
+			backSlashOpen = false
+		case pattern[i] == '\\':
+			backSlashOpen = true
+		case pattern[i] == '{':
+			stack++
+		case pattern[i] == '}':
+			if stack == 0 {
+				return i
+			}
+			stack--
+		}
+	}
+	return len(pattern)
+}
@@ -0,0 +1,38 @@
+package tree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlaceholder(t *testing.T) {
+	cases := map[string]Placeholder{
+		"":             {},
+		"*":            {},
+		"...":          {CatchAll: true},
+		"id":           {Name: "id"},
+		"id:[0-9]{2}":  {Name: "id", Regexp: "[0-9]{2}"},
+		"*:[0-9]+":     {Regexp: "[0-9]+"},
+		"path...":      {Name: "path", CatchAll: true},
+		"*path":        {Name: "path", CatchAll: true},
+		"user-id:uuid": {Name: "user-id", Regexp: "uuid"},
+	}
+	for body, expected := range cases {
+		p, err := ParsePlaceholder(body)
+		assert.NoError(t, err, body)
+		assert.Equal(t, expected, p, body)
+	}
+	for body, expected := range map[string]error{"id:": ErrInvalidRegexp, "i d": ErrInvalidParamName, "-id": ErrInvalidParamName} {
+		_, err := ParsePlaceholder(body)
+		assert.True(t, errors.Is(err, expected), body)
+	}
+}
+
+func TestPlaceholderEnd(t *testing.T) {
+	assert.Equal(t, 3, PlaceholderEnd("{id}", 0))
+	assert.Equal(t, 15, PlaceholderEnd("/{id:[0-9]{1,3}}/{a}", 1))
+	assert.Equal(t, 6, PlaceholderEnd("{a:\\{+}}", 0))
+	assert.Equal(t, 5, PlaceholderEnd("{a:{}", 0))
+}
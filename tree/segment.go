@@ -0,0 +1,216 @@
+package tree
+
+import (
+	"regexp"
+	"strings"
+)
+
+// segmentPart is static text or placeholder of a segment
+type segmentPart struct {
+	static string
+	param  bool
+	name   string
+	regexp string
+}
+
+// segment matches a path segment mixing static text and placeholders, e.g. "{name}.json", "v{version:int}" or "@{user}",
+// it is split by regexp, the leftmost placeholder takes the shortest value, e.g. "{a}-{b}" splits "x-y-z" into "x" and "y-z",
+// placeholder types are checked once segment is split
+type segment struct {
+	parts    []segmentPart
+	regexp   *regexp.Regexp
+	fold     *regexp.Regexp
+	typed    bool
+	groups   []int
+	matchers []MatchFunc
+	types    []string
+}
+
+// compileSegment compiles segment pattern returned by determineSegment
+func compileSegment(pattern string) (*segment, error) {
+	parts, _, err := parseSegment(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := &segment{parts: parts}
+	expr, foldExpr, group := "^", "^", 1
+	for _, part := range parts {
+		if !part.param {
+			expr += regexp.QuoteMeta(part.static)
+			foldExpr += "(?i:" + regexp.QuoteMeta(part.static) + ")"
+			continue
+		}
+		s.groups = append(s.groups, group)
+		group++
+		match, typ := Matcher(part.regexp), ""
+		if match != nil {
+			typ, s.typed = part.regexp, true
+		}
+		sub := "([^/]+?)"
+		if part.regexp != "" && match == nil {
+			rx, err := regexp.Compile(part.regexp)
+			if err != nil {
+				return nil, &PatternError{Err: ErrInvalidRegexp, Detail: err.Error()}
+			}
+			sub = "(" + part.regexp + ")"
+			group += rx.NumSubexp()
+		}
+		expr += sub
+		foldExpr += sub
+		s.matchers = append(s.matchers, match)
+		s.types = append(s.types, typ)
+	}
+	rx, err := regexp.Compile(expr + "$")
+	if err != nil {
+		return nil, &PatternError{Err: ErrInvalidRegexp, Detail: err.Error()}
+	}
+	s.regexp = rx
+	s.fold = regexp.MustCompile(foldExpr + "$")
+	return s, nil
+}
+
+func (s *segment) match(value string) bool {
+	if !s.typed {
+		return s.regexp.MatchString(value)
+	}
+	return s.submatches(value) != nil
+}
+
+// submatches returns submatch indexes of value, nil if value does not match
+func (s *segment) submatches(value string) []int {
+	idx := s.regexp.FindStringSubmatchIndex(value)
+	if idx == nil {
+		return nil
+	}
+	for i, match := range s.matchers {
+		if g := s.groups[i]; match != nil && !match(value[idx[2*g]:idx[2*g+1]]) {
+			return nil
+		}
+	}
+	return idx
+}
+
+// constrained reports whether a placeholder of segment has a regexp or type, e.g. "{year:[0-9]{4}}-{month}"
+func (s *segment) constrained() bool {
+	for _, part := range s.parts {
+		if part.param && part.regexp != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// canonical returns value with static text as registered for value of which static text differs in case
+func (s *segment) canonical(value string) (string, bool) {
+	idx := s.fold.FindStringSubmatchIndex(value)
+	if idx == nil {
+		return "", false
+	}
+	canonical, i := "", 0
+	for _, part := range s.parts {
+		if !part.param {
+			canonical += part.static
+			continue
+		}
+		param := s.value(value, idx, i)
+		if match := s.matchers[i]; match != nil && !match(param) {
+			return "", false
+		}
+		canonical += param
+		i++
+	}
+	return canonical, true
+}
+
+// value returns value of i-th placeholder from submatch indexes of value
+func (s *segment) value(value string, idx []int, i int) string {
+	g := s.groups[i]
+	return value[idx[2*g]:idx[2*g+1]]
+}
+
+// pattern returns segment with names of placeholders
+func (s *segment) pattern(names []string) string {
+	return segmentPattern(s.parts, names)
+}
+
+func segmentPattern(parts []segmentPart, names []string) string {
+	pattern, i := "", 0
+	for _, part := range parts {
+		if !part.param {
+			pattern += part.static
+			continue
+		}
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		i++
+		pattern += "{" + name
+		if part.regexp != "" {
+			pattern += ":" + part.regexp
+		}
+		pattern += "}"
+	}
+	return pattern
+}
+
+// determineSegment parses segment of str around placeholder at pos, it returns params of segment
+// and the segment pattern without param names, so that patterns differing by names share a node
+func determineSegment(str string, pos int) (nodeType nodeType, params []string, pattern string, segmentPos int, segmentLen int, err error) {
+	segmentPos = strings.LastIndexByte(str[:pos], '/') + 1
+	if segmentPos == 0 {
+		err = &PatternError{Err: ErrInvalidPlaceholder, Detail: "\"{\" must be followed by \"/\""}
+		return
+	}
+	parts, end, err := parseSegment(str, segmentPos)
+	if err != nil {
+		return
+	}
+	for _, part := range parts {
+		if part.param {
+			params = append(params, part.name)
+		}
+	}
+	return nodeTypeSegment, params, segmentPattern(parts, nil), segmentPos, end - segmentPos, nil
+}
+
+// parseSegment splits segment starting at start of str into static text and placeholders, it returns end of segment
+func parseSegment(str string, start int) (parts []segmentPart, end int, err error) {
+	i := start
+	for i < len(str) && str[i] != '/' {
+		if str[i] != '{' {
+			j := i
+			for j < len(str) && str[j] != '/' && str[j] != '{' {
+				j++
+			}
+			parts = append(parts, segmentPart{static: str[i:j]})
+			i = j
+			continue
+		}
+		if len(parts) > 0 && parts[len(parts)-1].param {
+			return nil, 0, &PatternError{Err: ErrInvalidPlaceholder, Detail: "placeholders must be separated by static text"}
+		}
+		closing := PlaceholderEnd(str, i)
+		if closing == len(str) {
+			return nil, 0, &PatternError{Err: ErrInvalidPlaceholder, Detail: "\"{\" is not closed"}
+		}
+		part, err := parseSegmentPlaceholder(str[i+1 : closing])
+		if err != nil {
+			return nil, 0, err
+		}
+		parts = append(parts, part)
+		i = closing + 1
+	}
+	return parts, i, nil
+}
+
+func parseSegmentPlaceholder(body string) (segmentPart, error) {
+	p, err := ParsePlaceholder(body)
+	if err != nil {
+		return segmentPart{}, err
+	}
+	if p.CatchAll {
+		return segmentPart{}, &PatternError{Err: ErrInvalidPlaceholder, Detail: "catch-all placeholder must fill a segment"}
+	}
+	return segmentPart{param: true, name: p.Name, regexp: p.Regexp}, nil
+}
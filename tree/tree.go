@@ -6,10 +6,24 @@
 // "/hello/{name}"
 // No placeholder name
 // "/hello/{} | /hello/{*}"
-// Regular expression matching
+// Regular expression matching, the expression has to match the whole segment
 // "/users/{id:[0-9]+}"
+// Placeholder types are matched by Go func instead of regexp, "int", "uuid" and "alpha" are built in,
+// others are added by RegisterMatcher
+// "/users/{id:int}"
 // No placeholder name
 // "/numbers/{:[0-9]+}|/numbers/{*:[0-9]+}"
+// Placeholders may be surrounded by static text in a segment, placeholders of a segment are separated by static text,
+// such segments are tried before segments made of a placeholder only, segments with constrained placeholders first
+// "/files/{name}.json | /v{version:int}/users | /@{user} | /{year:[0-9]{4}}-{month:[0-9]{2}}"
+// Optional parts in brackets may be left out of path, an optional placeholder takes its leading slash with it
+// "/posts[/{page:[0-9]+}] | /posts/{page?:[0-9]+} | /archive[/{year}[/{month}]]"
+// Brackets outside placeholders always mark optional parts, paths with literal "[" or "]" are matched by placeholders
+// "/files/{name:.*\[[0-9]+\]}"
+// Catch-all matching the rest of the path, slashes included (must be the last placeholder)
+// "/static/{path...} | /static/{*path}"
+// No placeholder name
+// "/static/{...}"
 
 package tree
 
@@ -18,17 +32,68 @@ import (
 	"regexp"
 )
 
+var (
+	ErrInvalidPlaceholder = errors.New("invalid placeholder")
+	ErrInvalidParamName   = errors.New("invalid param name")
+	ErrDuplicateParam     = errors.New("duplicate param name")
+	ErrParamConflict      = errors.New("params conflict with previously registered pattern")
+	ErrInvalidRegexp      = errors.New("invalid regexp")
+	ErrAmbiguousPattern   = errors.New("ambiguous with previously registered pattern")
+	ErrInvalidOptional    = errors.New("invalid optional part")
+)
+
+// PatternError reports a pattern which can not be added, Err is one of the Err* values above
+type PatternError struct {
+	Pattern string
+	Err     error
+	Detail  string
+}
+
+func (e *PatternError) Error() string {
+	msg := "pattern '" + e.Pattern + "': " + e.Err.Error()
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
 type nodeType uint8
 
 const (
 	nodeTypeStatic nodeType = iota
 	nodeTypeRegexp
 	nodeTypeWild
+	nodeTypeCatchAll
+	nodeTypeMatcher
+	nodeTypeSegment
 )
 
 type leaf struct {
 	params  []string
+	types   []string
 	context interface{}
+	node    *node
+}
+
+// leaf keeps its identity when edges are split, so it is returned as the registered NodeInterface
+func (l *leaf) FullPathPattern() string {
+	return l.node.FullPathPattern()
+}
+
+func (l *leaf) Context() interface{} {
+	return l.context
+}
+
+func (l *leaf) Params() []string {
+	return l.params
+}
+
+func (l *leaf) ParamTypes() []string {
+	return l.types
 }
 
 type node struct {
@@ -40,7 +105,9 @@ type node struct {
 	staticBranches []*node
 	regexpBranches []*node
 	wildBranch     *node
-	regexp         *regexp.Regexp
+	catchAllBranch *node
+	match          MatchFunc
+	segment        *segment
 	leaf           *leaf
 }
 
@@ -60,12 +127,25 @@ func (n *node) FullPathPattern() string {
 				p = "{" + params[pIdx] + "}"
 				pIdx--
 			}
-		} else if cn.nodeType == nodeTypeRegexp {
+		} else if cn.nodeType == nodeTypeRegexp || cn.nodeType == nodeTypeMatcher {
 			p = "{:" + cn.path + "}"
 			if pIdx >= 0 {
 				p = "{" + params[pIdx] + ":" + cn.path + "}"
 				pIdx--
 			}
+		} else if cn.nodeType == nodeTypeSegment {
+			count := len(cn.segment.groups)
+			p = cn.segment.pattern(nil)
+			if pIdx+1 >= count {
+				p = cn.segment.pattern(params[pIdx+1-count : pIdx+1])
+				pIdx -= count
+			}
+		} else if cn.nodeType == nodeTypeCatchAll {
+			p = "{...}"
+			if pIdx >= 0 {
+				p = "{" + params[pIdx] + "...}"
+				pIdx--
+			}
 		}
 		path = p + path
 		cn = cn.parent
@@ -87,26 +167,209 @@ func (n *node) Params() (params []string) {
 	return
 }
 
+func (n *node) ParamTypes() (types []string) {
+	if n.leaf != nil {
+		types = n.leaf.types
+	}
+	return
+}
+
+// paramTypes returns placeholder types of params on path to n, empty for params without type
+func (n *node) paramTypes(count int) []string {
+	types := make([]string, count)
+	idx := count - 1
+	for cn := n; cn != nil && idx >= 0; cn = cn.parent {
+		switch cn.nodeType {
+		case nodeTypeMatcher:
+			types[idx] = cn.path
+			idx--
+		case nodeTypeSegment:
+			for i := len(cn.segment.types) - 1; i >= 0 && idx >= 0; i-- {
+				types[idx] = cn.segment.types[i]
+				idx--
+			}
+		case nodeTypeWild, nodeTypeRegexp, nodeTypeCatchAll:
+			idx--
+		}
+	}
+	return types
+}
+
 func (n *node) Add(pattern string, ctx interface{}) NodeInterface {
 	return n.AddThen(pattern, func(context interface{}) interface{} {
 		return ctx
 	})
 }
 
+// AddThen panics with *PatternError if pattern is malformed or its params conflict with a registered pattern
 func (n *node) AddThen(pattern string, callback AddHookFunc) NodeInterface {
+	leaf, err := n.addThen(pattern, callback, false)
+	if err != nil {
+		panic(err)
+	}
+	return leaf
+}
+
+// TryAddThen works like AddThen but returns *PatternError instead of panicking,
+// it additionally rejects regexp, typed and segment placeholders registered at the same position as one matching
+// everything the other matches, e.g. "{id:[0-9]+}" and "{id:int}" or "{a}-{b}" and "{year:[0-9]{4}}-{month:[0-9]{2}}",
+// as one of them shadows the other. Partly overlapping placeholders such as "{id:[0-5]+}" and "{id:[3-9]+}" are
+// accepted, as well as placeholders overlapping wildcards, which are tried after them
+func (n *node) TryAddThen(pattern string, callback AddHookFunc) (NodeInterface, error) {
+	leaf, err := n.addThen(pattern, callback, true)
+	if err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// addThen adds each variant of pattern, callback is called for each of them,
+// leaf of the variant with all optional parts is returned
+func (n *node) addThen(pattern string, callback AddHookFunc, strict bool) (*leaf, error) {
+	variants, err := Variants(pattern)
+	if err != nil {
+		err.(*PatternError).Pattern = pattern
+		return nil, err
+	}
+	var first *leaf
+	for _, variant := range variants {
+		leaf, err := n.addVariant(variant, callback, strict)
+		if err != nil {
+			err.(*PatternError).Pattern = pattern
+			return nil, err
+		}
+		if first == nil {
+			first = leaf
+		}
+	}
+	return first, nil
+}
+
+func (n *node) addVariant(pattern string, callback AddHookFunc, strict bool) (*leaf, error) {
 	var params []string
-	treetop := n.add(pattern, &params)
+	treetop, err := n.add(pattern, &params, strict)
+	if err != nil {
+		treetop.prune().mergeStaticChild() //undo nodes inserted before the error
+		return nil, err
+	}
 	if treetop.leaf != nil {
 		if !isSameSlice(treetop.leaf.params, params) {
-			panic(errors.New("param in path is conflict prev registered"))
+			return nil, &PatternError{pattern, ErrParamConflict, "'" + treetop.FullPathPattern() + "'"}
 		}
 	} else {
-		treetop.leaf = &leaf{params: params}
+		treetop.leaf = &leaf{params: params, types: treetop.paramTypes(len(params)), node: treetop}
 	}
 	if callback != nil && treetop.leaf != nil {
 		treetop.leaf.context = callback(treetop.leaf.context)
 	}
-	return treetop
+	return treetop.leaf, nil
+}
+
+// Find returns registered node of pattern, params must be named as registered, nil if not found,
+// node of the variant with all optional parts is returned for pattern with optional parts
+func (n *node) Find(pattern string) NodeInterface {
+	if variants, err := Variants(pattern); err == nil {
+		pattern = variants[0]
+	}
+	var params []string
+	if found := n.find(pattern, &params); found != nil && found.leaf != nil && isSameSlice(found.leaf.params, params) {
+		return found.leaf
+	}
+	return nil
+}
+
+// Remove removes pattern, nodes left empty are pruned and edges split by adding are merged again,
+// so that tree is the same as built without pattern, it reports whether pattern was registered,
+// each variant of pattern with optional parts is removed
+func (n *node) Remove(pattern string) bool {
+	variants, err := Variants(pattern)
+	if err != nil {
+		return false
+	}
+	removed := false
+	for _, variant := range variants {
+		removed = n.removeVariant(variant) || removed
+	}
+	return removed
+}
+
+func (n *node) removeVariant(pattern string) bool {
+	var params []string
+	found := n.find(pattern, &params)
+	if found == nil || found.leaf == nil || !isSameSlice(found.leaf.params, params) {
+		return false
+	}
+	found.leaf = nil
+	found.prune().mergeStaticChild()
+	return true
+}
+
+// Walk calls walkFunc for each registered pattern, static branches first then regexp, wild and catch-all,
+// walking stops at the first error returned by walkFunc
+func (n *node) Walk(walkFunc WalkFunc) error {
+	if n.leaf != nil {
+		if err := walkFunc(n.leaf); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.staticBranches {
+		if child != nil {
+			if err := child.Walk(walkFunc); err != nil {
+				return err
+			}
+		}
+	}
+	for _, child := range n.regexpBranches {
+		if err := child.Walk(walkFunc); err != nil {
+			return err
+		}
+	}
+	if n.wildBranch != nil {
+		if err := n.wildBranch.Walk(walkFunc); err != nil {
+			return err
+		}
+	}
+	if n.catchAllBranch != nil {
+		return n.catchAllBranch.Walk(walkFunc)
+	}
+	return nil
+}
+
+// Clone returns a deep copy of tree, contexts of patterns are copied by copyContext, or shared if it is nil,
+// so that the copy can be modified while the tree is in use
+func (n *node) Clone(copyContext CopyFunc) TrieInterface {
+	return n.clone(nil, copyContext)
+}
+
+func (n *node) clone(parent *node, copyContext CopyFunc) *node {
+	c := new(node)
+	*c = *n
+	c.parent = parent
+	c.staticBranches = make([]*node, len(n.staticBranches))
+	for i, child := range n.staticBranches {
+		if child != nil {
+			c.staticBranches[i] = child.clone(c, copyContext)
+		}
+	}
+	if n.regexpBranches != nil {
+		c.regexpBranches = make([]*node, len(n.regexpBranches))
+		for i, child := range n.regexpBranches {
+			c.regexpBranches[i] = child.clone(c, copyContext)
+		}
+	}
+	if n.wildBranch != nil {
+		c.wildBranch = n.wildBranch.clone(c, copyContext)
+	}
+	if n.catchAllBranch != nil {
+		c.catchAllBranch = n.catchAllBranch.clone(c, copyContext)
+	}
+	if n.leaf != nil {
+		c.leaf = &leaf{params: n.leaf.params, types: n.leaf.types, context: n.leaf.context, node: c}
+		if copyContext != nil {
+			c.leaf.context = copyContext(n.leaf.context)
+		}
+	}
+	return c
 }
 
 func NewTree() *node {
@@ -130,52 +393,130 @@ func isSameSlice(a []string, b []string) bool {
 	return true
 }
 
-func (n *node) Lookup(path string, fixTailingSlash bool) (ctx interface{}, pairs []*Pair, ok bool) {
+func (n *node) Lookup(path string, fixTailingSlash bool) (ctx interface{}, pairs []Pair, ok bool) {
+	return n.LookupAppend(path, fixTailingSlash, nil)
+}
+
+// LookupAppend appends params of matched pattern to pairs, so that caller can reuse the buffer of pairs
+func (n *node) LookupAppend(path string, fixTailingSlash bool, pairs []Pair) (ctx interface{}, _ []Pair, ok bool) {
 	var leaf *leaf
-	if leaf, pairs = n.lookUp(path, fixTailingSlash); leaf != nil {
+	if leaf, pairs = n.lookUp(path, fixTailingSlash, pairs); leaf != nil {
 		return leaf.context, pairs, true
 	}
 	return ctx, pairs, false
 }
 
-func paramsAppend(params []string, param string) []string {
+func paramsAppend(params []string, param string) ([]string, error) {
 	if param == "*" {
 		param = ""
 	}
 	if param != "" {
 		for _, v := range params {
 			if v == param {
-				panic(errors.New("param name '" + param + "' duplicate"))
+				return params, &PatternError{Err: ErrDuplicateParam, Detail: "'" + param + "'"}
 			}
 		}
 	}
-	return append(params, param)
+	return append(params, param), nil
 }
 
-func (n *node) add(path string, params *[]string) *node {
-	nodeType, param, regexPattern, bracesPos, bracesLen := determinePlaceholder(path)
-	if nodeType == nodeTypeWild || nodeType == nodeTypeRegexp {
-		*params = paramsAppend(*params, param)
+// add inserts path, errors returned are *PatternError without Pattern, which is filled by addThen,
+// the deepest node inserted is returned along with error so that it can be pruned
+func (n *node) add(path string, params *[]string, strict bool) (*node, error) {
+	nodeType, names, regexPattern, bracesPos, bracesLen, err := determinePlaceholder(path)
+	if err != nil {
+		return n, err
+	}
+	if nodeType != nodeTypeStatic {
+		for _, param := range names {
+			if *params, err = paramsAppend(*params, param); err != nil {
+				return n, err
+			}
+		}
 		if nodeType == nodeTypeWild {
 			return n.insertStaticNode(path[:bracesPos]).
-				insertWildNode(path[bracesPos+bracesLen:], params)
+				insertWildNode(path[bracesPos+bracesLen:], params, strict)
+		} else if nodeType == nodeTypeCatchAll {
+			return n.insertStaticNode(path[:bracesPos]).insertCatchAllNode(), nil
 		} else {
 			return n.insertStaticNode(path[:bracesPos]).
-				insertRegexpNode(regexPattern, path[bracesPos+bracesLen:], params)
+				insertRegexpNode(nodeType, regexPattern, path[bracesPos+bracesLen:], params, strict)
+		}
+	}
+	return n.insertStaticNode(path), nil
+}
+
+// find returns node of path the same way as add, without inserting anything
+func (n *node) find(path string, params *[]string) *node {
+	nodeType, names, regexPattern, bracesPos, bracesLen, err := determinePlaceholder(path)
+	if err != nil {
+		return nil
+	}
+	if nodeType == nodeTypeStatic {
+		return n.findStaticNode(path)
+	}
+	for _, param := range names {
+		if *params, err = paramsAppend(*params, param); err != nil {
+			return nil
+		}
+	}
+	parent := n.findStaticNode(path[:bracesPos])
+	if parent == nil {
+		return nil
+	}
+	var child *node
+	switch nodeType {
+	case nodeTypeCatchAll:
+		return parent.catchAllBranch
+	case nodeTypeWild:
+		child = parent.wildBranch
+	default:
+		for _, branch := range parent.regexpBranches {
+			if branch.path == regexPattern {
+				child = branch
+			}
+		}
+	}
+	tail := clearPrefixSlash(path[bracesPos+bracesLen:])
+	if child == nil || tail == "" {
+		return child
+	}
+	return child.find(tail, params)
+}
+
+func (n *node) findStaticNode(path string) *node {
+	path = cleanDuplicateSlash(path)
+	for {
+		i := 0
+		if n.nodeType == nodeTypeStatic {
+			if len(path) < n.pathLen || path[:n.pathLen] != n.path {
+				return nil
+			}
+			i = n.pathLen
+		}
+		if i == len(path) {
+			return n
+		}
+		if n = n.staticBranches[path[i]]; n == nil {
+			return nil
 		}
+		path = path[i:]
 	}
-	return n.insertStaticNode(path)
 }
 
-func determinePlaceholder(str string) (nodeType nodeType, param string, regexpPattern string, bracesPos int, bracesLen int) {
+// determinePlaceholder finds the first placeholder of str, segments mixing static text and placeholders are
+// determined by determineSegment
+func determinePlaceholder(str string) (nodeType nodeType, params []string, regexpPattern string, bracesPos int, bracesLen int, err error) {
+	var param string
 	i, bracesStack, bracesEnd := 0, 0, 0
 	backSlashOpen := false
 	regexpPattern = ""
 	for ; i < len(str); i++ {
 		if bracesPos == 0 { //lookUp for placeholder start "/"
 			if str[i] == '{' {
-				if i == 0 || str[i-1] != '/' {
-					panic(errors.New("placeholder \"{\" must be followed by \"/\""))
+				if i == 0 {
+					err = &PatternError{Err: ErrInvalidPlaceholder, Detail: "\"{\" must be followed by \"/\""}
+					return
 				}
 				bracesPos = i
 			}
@@ -185,7 +526,8 @@ func determinePlaceholder(str string) (nodeType nodeType, param string, regexpPa
 			} else if str[i] == '}' && !backSlashOpen {
 				if bracesStack == 0 {
 					if len(regexpPattern) == 0 {
-						panic(errors.New("regexp pattern is empty"))
+						err = &PatternError{Err: ErrInvalidRegexp, Detail: "regexp pattern is empty"}
+						return
 					}
 					bracesEnd = i
 					break
@@ -210,19 +552,32 @@ func determinePlaceholder(str string) (nodeType nodeType, param string, regexpPa
 		}
 	}
 	if bracesPos > 0 && str[bracesEnd] == '}' {
-		if i+1 != len(str) && str[i+1] != '/' {
-			panic(errors.New("placeholder \"}\" must be at the end or before \"/\""))
+		if str[bracesPos-1] != '/' || i+1 != len(str) && str[i+1] != '/' {
+			return determineSegment(str, bracesPos)
 		}
 		if nodeType != nodeTypeRegexp {
 			nodeType = nodeTypeWild
+			if len(param) > 3 && param[len(param)-3:] == "..." || param == "..." {
+				nodeType, param = nodeTypeCatchAll, param[:len(param)-3]
+			} else if len(param) > 1 && param[0] == '*' {
+				nodeType, param = nodeTypeCatchAll, param[1:]
+			}
+			if nodeType == nodeTypeCatchAll && i+1 != len(str) {
+				err = &PatternError{Err: ErrInvalidPlaceholder, Detail: "catch-all placeholder must be at the end of pattern"}
+				return
+			}
+		}
+		if param != "*" && !ValidParamName(param) {
+			err = &PatternError{Err: ErrInvalidParamName, Detail: "\"" + param + "\""}
+			return
 		}
-		reg, _ := regexp.Compile("^$|^\\*$|^[a-zA-Z0-9_]+(-*[a-zA-Z0-9_]+)*$")
-		if !reg.Match([]byte(param)) {
-			panic(errors.New("invalid param name \"" + param + "\""))
+		if nodeType == nodeTypeRegexp && Matcher(regexpPattern) != nil {
+			nodeType = nodeTypeMatcher
 		}
 		bracesLen = (bracesEnd - bracesPos) + 1
+		params = []string{param}
 	} else {
-		nodeType, param, regexpPattern, bracesPos, bracesLen = nodeTypeStatic, "", "", 0, 0
+		nodeType, regexpPattern, bracesPos, bracesLen = nodeTypeStatic, "", 0, 0
 	}
 	return
 }
@@ -260,7 +615,7 @@ func (n *node) insertStaticNode(path string) *node {
 	return n
 }
 
-func (n *node) insertWildNode(tail string, params *[]string) *node {
+func (n *node) insertWildNode(tail string, params *[]string, strict bool) (*node, error) {
 	n.hasNonStatic = true
 	if n.wildBranch == nil {
 		n.wildBranch = newNode()
@@ -270,38 +625,81 @@ func (n *node) insertWildNode(tail string, params *[]string) *node {
 	}
 	tail = clearPrefixSlash(tail)
 	if tail == "" {
-		return n.wildBranch
+		return n.wildBranch, nil
 	}
-	return n.wildBranch.add(tail, params)
+	return n.wildBranch.add(tail, params, strict)
 }
 
-func (n *node) insertRegexpNode(regexPattern string, tail string, params *[]string) *node {
-	var regexpNode *node
+func (n *node) insertCatchAllNode() *node {
 	n.hasNonStatic = true
+	if n.catchAllBranch == nil {
+		n.catchAllBranch = newNode()
+		n.catchAllBranch.parent = n
+		n.catchAllBranch.nodeType = nodeTypeCatchAll
+		n.catchAllBranch.path = "{...}"
+	}
+	return n.catchAllBranch
+}
+
+// insertRegexpNode inserts regexp, matcher or segment node, segment nodes are tried first by lookUp, segments with
+// regexp or typed placeholders before those of placeholders matching anything, then regexp and matcher nodes,
+// nodes of the same kind are tried in order of insertion
+func (n *node) insertRegexpNode(nodeType nodeType, regexPattern string, tail string, params *[]string, strict bool) (*node, error) {
+	var regexpNode *node
 	for k := range n.regexpBranches {
-		if regexPattern == n.regexpBranches[k].regexp.String() {
+		if regexPattern == n.regexpBranches[k].path {
 			regexpNode = n.regexpBranches[k]
 			break
 		}
 	}
 	if regexpNode == nil {
+		var seg *segment
+		match := Matcher(regexPattern)
+		if nodeType == nodeTypeSegment {
+			var err error
+			if seg, err = compileSegment(regexPattern); err != nil {
+				return n, err
+			}
+			match = seg.match
+		} else if nodeType == nodeTypeRegexp {
+			rx, err := regexp.Compile("^(?:" + regexPattern + ")$")
+			if err != nil {
+				return n, &PatternError{Err: ErrInvalidRegexp, Detail: err.Error()}
+			}
+			match = rx.MatchString
+		}
+		if strict {
+			for _, branch := range n.regexpBranches {
+				if overlaps(branch.nodeType, branch.path, nodeType, regexPattern) {
+					return n, &PatternError{Err: ErrAmbiguousPattern, Detail: "'" + regexPattern + "' overlaps '" + branch.path + "'"}
+				}
+			}
+		}
 		regexpNode = newNode()
 		regexpNode.path = regexPattern
-		regexpNode.nodeType = nodeTypeRegexp
+		regexpNode.nodeType = nodeType
 		regexpNode.parent = n
-		if rx, err := regexp.Compile(regexPattern); err != nil {
-			panic(err)
-		} else {
-			regexpNode.regexp = rx
+		regexpNode.match = match
+		regexpNode.segment = seg
+		pos := len(n.regexpBranches)
+		if nodeType == nodeTypeSegment { //after segments tried first, constrained ones before unconstrained ones
+			for pos = 0; pos < len(n.regexpBranches) && n.regexpBranches[pos].nodeType == nodeTypeSegment; pos++ {
+				if seg.constrained() && !n.regexpBranches[pos].segment.constrained() {
+					break
+				}
+			}
 		}
-		n.regexpBranches = append(n.regexpBranches, regexpNode)
+		n.regexpBranches = append(n.regexpBranches, nil)
+		copy(n.regexpBranches[pos+1:], n.regexpBranches[pos:])
+		n.regexpBranches[pos] = regexpNode
 	}
+	n.hasNonStatic = true
 
 	tail = clearPrefixSlash(tail)
 	if tail == "" {
-		return regexpNode
+		return regexpNode, nil
 	}
-	return regexpNode.add(tail, params)
+	return regexpNode.add(tail, params, strict)
 }
 
 func clearPrefixSlash(p string) (ret string) {
@@ -339,6 +737,72 @@ func (n *node) updateParentOfBranches() {
 	if n.wildBranch != nil {
 		n.wildBranch.parent = n
 	}
+	if n.catchAllBranch != nil {
+		n.catchAllBranch.parent = n
+	}
+}
+
+func (n *node) isEmpty() bool {
+	return n.leaf == nil && !n.hasNonStatic && !n.hasStaticBranches()
+}
+
+// prune detaches empty nodes from n up to root, it returns the first node left
+func (n *node) prune() *node {
+	for n.parent != nil && n.isEmpty() {
+		parent := n.parent
+		switch n.nodeType {
+		case nodeTypeStatic:
+			parent.staticBranches[n.path[0]] = nil
+		case nodeTypeWild:
+			parent.wildBranch = nil
+		case nodeTypeCatchAll:
+			parent.catchAllBranch = nil
+		case nodeTypeRegexp, nodeTypeMatcher, nodeTypeSegment:
+			for i, branch := range parent.regexpBranches {
+				if branch == n {
+					parent.regexpBranches = append(parent.regexpBranches[:i], parent.regexpBranches[i+1:]...)
+					break
+				}
+			}
+		}
+		parent.hasNonStatic = len(parent.regexpBranches) > 0 || parent.wildBranch != nil || parent.catchAllBranch != nil
+		n = parent
+	}
+	if n.parent == nil && n.isEmpty() { //empty root takes the next added path
+		n.path, n.pathLen = "", 0
+	}
+	return n
+}
+
+// mergeStaticChild merges the only static child into n, which reverses splitEdge
+func (n *node) mergeStaticChild() {
+	if n.nodeType != nodeTypeStatic || n.leaf != nil || n.hasNonStatic || n.path == "" {
+		return
+	}
+	var child *node
+	for _, c := range n.staticBranches {
+		if c != nil {
+			if child != nil {
+				return
+			}
+			child = c
+		}
+	}
+	if child == nil {
+		return
+	}
+	n.path += child.path
+	n.pathLen = len(n.path)
+	n.hasNonStatic = child.hasNonStatic
+	n.staticBranches = child.staticBranches
+	n.regexpBranches = child.regexpBranches
+	n.wildBranch = child.wildBranch
+	n.catchAllBranch = child.catchAllBranch
+	n.leaf = child.leaf
+	n.updateParentOfBranches()
+	if n.leaf != nil {
+		n.leaf.node = n
+	}
 }
 
 func (n *node) splitEdge(pos int) {
@@ -351,18 +815,25 @@ func (n *node) splitEdge(pos int) {
 		staticBranches: n.staticBranches,
 		regexpBranches: n.regexpBranches,
 		wildBranch:     n.wildBranch,
-		regexp:         n.regexp,
+		catchAllBranch: n.catchAllBranch,
+		match:          n.match,
+		segment:        n.segment,
 		leaf:           n.leaf,
 	}
 	branch.updateParentOfBranches()
+	if branch.leaf != nil {
+		branch.leaf.node = branch
+	}
 	n.path = n.path[:pos]
 	n.pathLen = len(n.path)
 	n.staticBranches = make([]*node, 256)
 	n.staticBranches[branch.path[0]] = branch
-	n.regexpBranches = n.regexpBranches[:0]
+	n.regexpBranches = nil
 	n.wildBranch = nil
+	n.catchAllBranch = nil
 	n.hasNonStatic = false
-	n.regexp = nil
+	n.match = nil
+	n.segment = nil
 	n.leaf = nil
 }
 
@@ -384,8 +855,8 @@ type nodeStack struct {
 }
 
 type backStateStack struct {
-	//previous backBtack
-	prev *backStateStack
+	//index of previous backStack, -1 for none
+	prev int
 	//state node
 	node *node
 	//path start position
@@ -400,13 +871,18 @@ type backStateStack struct {
 	paramLen int
 	//wild node process done
 	wildDone bool
+	//catch-all node process done
+	catchAllDone bool
 }
 
-func (n *node) lookUp(path string, fixTailingSlash bool) (*leaf, []*Pair) { //to speed up , no others func call
-	var backStack, pevStack *backStateStack
+func (n *node) lookUp(path string, fixTailingSlash bool, pairs []Pair) (*leaf, []Pair) { //to speed up , no others func call
+	//back states are kept in a slice backed by an array on stack, so that common lookups do not allocate
+	var stackBuf [8]backStateStack
+	stack := stackBuf[:0]
+	backStack, pevStack := -1, -1
 	var leaf *leaf
 	var next *node
-	var prefixMatch, wildDone bool
+	var prefixMatch, wildDone, catchAllDone bool
 	var po, regexpIdx, paramPo, paramLen int
 
 walk:
@@ -415,7 +891,7 @@ walk:
 	switch n.nodeType {
 	case nodeTypeStatic:
 		if !prefixMatch {
-			regexpIdx, paramPo, paramLen, wildDone = 0, 0, 0, false
+			regexpIdx, paramPo, paramLen, wildDone, catchAllDone = 0, 0, 0, false, false
 			if len(path[po:]) > n.pathLen {
 				if p := path[po : po+n.pathLen]; p == n.path {
 					prefixMatch = true
@@ -456,7 +932,7 @@ walk:
 				regexpNode := n.regexpBranches[regexpIdx]
 				regexpIdx++
 
-				if m := regexpNode.regexp.FindAllString(path[paramPo:paramPo+paramLen], 1); len(m) > 0 && m[0] == path[paramPo:paramPo+paramLen] {
+				if regexpNode.match(path[paramPo : paramPo+paramLen]) {
 					next = regexpNode
 					goto beforeNext
 				}
@@ -470,8 +946,18 @@ walk:
 					goto beforeNext
 				}
 			}
+
+			//catch-all, takes the rest of path
+			if !catchAllDone {
+				catchAllDone = true
+				if n.catchAllBranch != nil {
+					paramLen = len(path) - paramPo
+					next = n.catchAllBranch
+					goto beforeNext
+				}
+			}
 		}
-	case nodeTypeWild, nodeTypeRegexp:
+	case nodeTypeWild, nodeTypeRegexp, nodeTypeMatcher, nodeTypeSegment:
 		if npo := po + paramLen; len(path) == npo && n.leaf != nil {
 			leaf = n.leaf
 			goto found
@@ -485,41 +971,50 @@ walk:
 				goto found
 			}
 		}
-
+	case nodeTypeCatchAll:
+		if n.leaf != nil {
+			leaf = n.leaf
+			goto found
+		}
 	}
-	if backStack != nil {
-		n = backStack.node
-		po = backStack.po
-		prefixMatch = backStack.prefixMatch
-		wildDone = backStack.wildDone
-		regexpIdx = backStack.regexpIdx
-		paramPo = backStack.paramPo
-		paramLen = backStack.paramLen
+	if backStack >= 0 {
+		state := &stack[backStack]
+		n = state.node
+		po = state.po
+		prefixMatch = state.prefixMatch
+		wildDone = state.wildDone
+		catchAllDone = state.catchAllDone
+		regexpIdx = state.regexpIdx
+		paramPo = state.paramPo
+		paramLen = state.paramLen
 		pevStack = backStack
-		backStack = backStack.prev
+		backStack = state.prev
 		goto walk
 	}
-	return nil, []*Pair{}
+	return nil, pairs
 
 beforeNext:
 	if next != nil {
 		if n.hasNonStatic {
-			if backStack == nil || backStack.node != n {
-				if pevStack != nil && pevStack.node == n {
+			if backStack < 0 || stack[backStack].node != n {
+				if pevStack >= 0 && stack[pevStack].node == n {
 					backStack = pevStack
 				} else {
-					backStack = &backStateStack{
+					stack = append(stack, backStateStack{
 						prev:        backStack,
 						node:        n,
 						po:          po,
 						prefixMatch: prefixMatch,
-					}
+					})
+					backStack = len(stack) - 1
 				}
 			}
-			backStack.regexpIdx = regexpIdx
-			backStack.paramPo = paramPo
-			backStack.paramLen = paramLen
-			backStack.wildDone = wildDone
+			state := &stack[backStack]
+			state.regexpIdx = regexpIdx
+			state.paramPo = paramPo
+			state.paramLen = paramLen
+			state.wildDone = wildDone
+			state.catchAllDone = catchAllDone
 		}
 		n = next
 		po = nextPo
@@ -527,15 +1022,34 @@ beforeNext:
 		goto walk
 	}
 found:
-	paramList := make([]*Pair, len(leaf.params), len(leaf.params))
-	paramsIdx := len(paramList) - 1
-	for paramsIdx >= 0 && backStack != nil {
-		if backStack.paramLen > 0 {
-			paramList[paramsIdx] = &Pair{leaf.params[paramsIdx], path[backStack.paramPo : backStack.paramPo+backStack.paramLen]}
-			paramsIdx--
+	base := len(pairs)
+	if total := base + len(leaf.params); total <= cap(pairs) {
+		pairs = pairs[:total]
+	} else {
+		pairs = append(pairs, make([]Pair, len(leaf.params))...)
+	}
+	paramsIdx := len(leaf.params) - 1
+	for paramsIdx >= 0 && backStack >= 0 {
+		if state := &stack[backStack]; state.paramLen > 0 {
+			value := path[state.paramPo : state.paramPo+state.paramLen]
+			//regexp branches are tried before wild one, regexpIdx is one past the branch taken
+			var seg *segment
+			if !state.wildDone && state.regexpIdx > 0 {
+				seg = state.node.regexpBranches[state.regexpIdx-1].segment
+			}
+			if seg != nil {
+				idx := seg.submatches(value)
+				for i := len(seg.groups) - 1; i >= 0 && paramsIdx >= 0; i-- {
+					pairs[base+paramsIdx] = Pair{leaf.params[paramsIdx], seg.value(value, idx, i)}
+					paramsIdx--
+				}
+			} else {
+				pairs[base+paramsIdx] = Pair{leaf.params[paramsIdx], value}
+				paramsIdx--
+			}
 		}
-		backStack = backStack.prev
+		backStack = stack[backStack].prev
 	}
 
-	return leaf, paramList
+	return leaf, pairs
 }
@@ -1,6 +1,7 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -22,6 +23,9 @@ func dumpNode(n *node, prefix string) {
 	if n.wildBranch != nil {
 		dumpNode(n.wildBranch, prefix)
 	}
+	if n.catchAllBranch != nil {
+		dumpNode(n.catchAllBranch, prefix)
+	}
 }
 
 func pairsString(pairs []*Pair) string {
@@ -84,18 +88,18 @@ func assertFoundParams(t *testing.T, tree *node, lookup string, fixTailingSlash
 	}
 	if expectedParams != nil {
 		if len(p) != len(expectedParams) {
-			assert.Fail(t, "expected: "+pairsString(expectedParams)+" actual: "+pairsString(p))
+			assert.Fail(t, "expected: "+pairsString(expectedParams)+" actual: "+fmt.Sprintf("%v", p))
 		} else {
 			for _, v := range expectedParams {
 				found := false
 				for _, pv := range p {
 					//fmt.Println(pv)
-					if pv != nil && v.Name == pv.Name && v.Value == pv.Value {
+					if v.Name == pv.Name && v.Value == pv.Value {
 						found = true
 					}
 				}
 				if !found {
-					assert.Fail(t, fmt.Sprintf("expected &Pair{Name: \"%s\", Value: \"%s\"} not found ", v.Name, v.Value)+" expected: "+pairsString(expectedParams)+" actual: "+pairsString(p))
+					assert.Fail(t, fmt.Sprintf("expected &Pair{Name: \"%s\", Value: \"%s\"} not found ", v.Name, v.Value)+" expected: "+pairsString(expectedParams)+" actual: "+fmt.Sprintf("%v", p))
 				}
 			}
 		}
@@ -241,11 +245,71 @@ func TestAddWildAndRegexpWithInvalidParamNameShouldPanic(t *testing.T) {
 
 func TestAddInvalidWildOrRegexpPathShouldPanic(t *testing.T) {
 	tree := NewTree()
-	assertAddPanic(t, tree, "/path/to/a{param}/")
-	assertAddPanic(t, tree, "/path/to/{param}_/")
-	assertAddPanic(t, tree, "/path/to/a{param:[a-z]{10}}/")
-	assertAddPanic(t, tree, "/path/to/{param:[a-z]{10}}_/")
+	assertAddPanic(t, tree, "{param}")
+	assertAddPanic(t, tree, "/path/to/{a}{b}/")
+	assertAddPanic(t, tree, "/path/to/a{param:}/")
 	assertAddPanic(t, tree, "/path/to/{param:}/")
+	assertAddPanic(t, tree, "/path/to/a{path...}")
+	assertAddPanic(t, tree, "/path/to/{a}.{b:[a-z}")
+	assertAddPanic(t, tree, "/path/to/{a}.{-b}")
+}
+
+func TestSegmentPlaceholders(t *testing.T) {
+	tree := NewTree()
+	assertAddExceptFullPath(t, tree, "/files/{name}.json", "json", "/files/{name}.json", []string{"name"})
+	assertAddExceptFullPath(t, tree, "/files/{name}", "wild", "/files/{name}")
+	assertAddExceptFullPath(t, tree, "/files/{id:[0-9]+}", "regexp", "/files/{id:[0-9]+}")
+	assertAddExceptFullPath(t, tree, "/files/index.json", "static", "/files/index.json")
+	assertAddExceptFullPath(t, tree, "/v{version:int}/users", "version", "/v{version:int}/users")
+	assertAddExceptFullPath(t, tree, "/@{user}", "user", "/@{user}")
+	assertAddExceptFullPath(t, tree, "/{year:[0-9]{4}}-{month:([0-9]{2})}/{slug}.html", "date", "/{year:[0-9]{4}}-{month:([0-9]{2})}/{slug}.html")
+	assertAddExceptFullPath(t, tree, "/{a}-{b}/x", "pair", "/{a}-{b}/x")
+	assertAddExceptFullPath(t, tree, "/{}.txt", "unnamed", "/{}.txt")
+	assertAddPanic(t, tree, "/files/{other}.json")
+
+	assertFound(t, tree, "/files/index.json", false, "static")
+	assertFoundParams(t, tree, "/files/a.b.json", false, []*Pair{{"name", "a.b"}}, "json")
+	assertFoundParams(t, tree, "/files/12.json", false, []*Pair{{"name", "12"}}, "json")
+	assertFoundParams(t, tree, "/files/12", false, []*Pair{{"id", "12"}}, "regexp")
+	assertFoundParams(t, tree, "/files/.json", false, []*Pair{{"name", ".json"}}, "wild")
+	assertFoundParams(t, tree, "/v2/users", false, []*Pair{{"version", "2"}}, "version")
+	assertNotFound(t, tree, "/vx/users", false)
+	assertFoundParams(t, tree, "/@bob", false, []*Pair{{"user", "bob"}}, "user")
+	assertFoundParams(t, tree, "/2020-01/hello.html", false, []*Pair{{"year", "2020"}, {"month", "01"}, {"slug", "hello"}}, "date")
+	assertFoundParams(t, tree, "/x-y-z/x", false, []*Pair{{"a", "x"}, {"b", "y-z"}}, "pair")
+	assertFoundParams(t, tree, "/a.txt", false, []*Pair{{"", "a"}}, "unnamed")
+	assertNotFound(t, tree, "/2020-1/hello.html", false)
+
+	assert.Equal(t, []string{"int"}, tree.Find("/v{version:int}/users").ParamTypes())
+	assert.Nil(t, tree.Find("/files/{other}.json"))
+	assert.True(t, tree.Remove("/files/{name}.json"))
+	assertFoundParams(t, tree, "/files/a.json", false, []*Pair{{"name", "a.json"}}, "wild")
+}
+
+func TestSegmentShouldBacktrack(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/{name}.json/meta", "meta")
+	tree.Add("/{id:int}/{name}.json", "json")
+	tree.Add("/{a}/{b}", "wild")
+
+	assertFoundParams(t, tree, "/x.json/y", false, []*Pair{{"a", "x.json"}, {"b", "y"}}, "wild")
+	assertFoundParams(t, tree, "/x.json/meta", false, []*Pair{{"name", "x"}}, "meta")
+	assertFoundParams(t, tree, "/1/x.json", false, []*Pair{{"id", "1"}, {"name", "x"}}, "json")
+	assertFoundParams(t, tree, "/1/x.json/", true, []*Pair{{"id", "1"}, {"name", "x"}}, "json")
+}
+
+func TestSegmentConstrainedShouldBeTriedFirst(t *testing.T) {
+	for _, patterns := range [][]string{
+		{"/d/{a}-{b}", "/d/{year:[0-9]{4}}-{month:int}"},
+		{"/d/{year:[0-9]{4}}-{month:int}", "/d/{a}-{b}"},
+	} {
+		tree := NewTree()
+		for _, pattern := range patterns {
+			tree.Add(pattern, pattern)
+		}
+		assertFoundParams(t, tree, "/d/2020-01", false, []*Pair{{"year", "2020"}, {"month", "01"}}, "/d/{year:[0-9]{4}}-{month:int}")
+		assertFoundParams(t, tree, "/d/x-y", false, []*Pair{{"a", "x"}, {"b", "y"}}, "/d/{a}-{b}")
+	}
 }
 
 func TestAddInvalidRegexpPatternShouldPanic(t *testing.T) {
@@ -337,3 +401,313 @@ func TestAddDoubleSlashesShouldCleanToOneSlash(t *testing.T) {
 	assertFound(t, tree, "/1234/path/4444", false, 4)
 	assertNotFound(t, tree, "/1234//path//4444", false)
 }
+
+func TestCatchAllPathShouldOK(t *testing.T) {
+	tree := NewTree()
+	assertAddExceptFullPath(t, tree, "/static/{path...}", 1, "/static/{path...}", []string{"path"})
+	assertAddExceptFullPath(t, tree, "/files/{*rest}", 2, "/files/{rest...}", []string{"rest"})
+	assertAddExceptFullPath(t, tree, "/any/{...}", 3, "/any/{...}", []string{""})
+	assertAddExceptFullPath(t, tree, "/users/{id}/{tail...}", 4, "/users/{id}/{tail...}", []string{"id", "tail"})
+
+	assertFoundParams(t, tree, "/static/app.js", false, []*Pair{&Pair{"path", "app.js"}}, 1)
+	assertFoundParams(t, tree, "/static/js/vendor/app.js", false, []*Pair{&Pair{"path", "js/vendor/app.js"}}, 1)
+	assertFoundParams(t, tree, "/static/css/", false, []*Pair{&Pair{"path", "css/"}}, 1)
+	assertFoundParams(t, tree, "/files/a/b/c", false, []*Pair{&Pair{"rest", "a/b/c"}}, 2)
+	assertFoundParams(t, tree, "/any/a/b", false, []*Pair{&Pair{"", "a/b"}}, 3)
+	assertFoundParams(t, tree, "/users/12/a/b", false, []*Pair{&Pair{"id", "12"}, &Pair{"tail", "a/b"}}, 4)
+	assertNotFound(t, tree, "/static/", false)
+	assertNotFound(t, tree, "/static/", true)
+	assertNotFound(t, tree, "/static//a", false)
+	assertNotFound(t, tree, "/users/12", false)
+}
+
+func TestCatchAllPriority(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/static/{path...}", "catch-all")
+	tree.Add("/static/{file}", "wild")
+	tree.Add("/static/{id:[0-9]+}", "regexp")
+	tree.Add("/static/index.html", "static")
+	tree.Add("/static/{dir}/index.html", "wild-static")
+
+	assertFound(t, tree, "/static/index.html", false, "static")
+	assertFound(t, tree, "/static/123", false, "regexp")
+	assertFound(t, tree, "/static/abc", false, "wild")
+	assertFound(t, tree, "/static/abc/index.html", false, "wild-static")
+	assertFoundParams(t, tree, "/static/abc/def", false, []*Pair{&Pair{"path", "abc/def"}}, "catch-all")
+	assertFoundParams(t, tree, "/static/123/def", false, []*Pair{&Pair{"path", "123/def"}}, "catch-all")
+	assertFoundParams(t, tree, "/static/index.html/a", false, []*Pair{&Pair{"path", "index.html/a"}}, "catch-all")
+}
+
+func TestAddInvalidCatchAllShouldPanic(t *testing.T) {
+	tree := NewTree()
+	assertAddPanic(t, tree, "/static/{path...}/a")
+	assertAddPanic(t, tree, "/static/{*path}/")
+	assertAddPanic(t, tree, "/static/{path...:[a-z]+}")
+	assertAddPanic(t, tree, "/static/{pa.th...}")
+	tree.Add("/files/{*path}", nil)
+	assertAddPanic(t, tree, "/files/{rest...}")
+}
+
+func TestAddedNodeShouldSurviveSplitEdge(t *testing.T) {
+	tree := NewTree()
+	about := tree.Add("/about", 1)
+	tree.Add("/any/{}", 2)
+	tree.Add("/a", 3)
+	assert.Equal(t, "/about", about.FullPathPattern())
+	assert.Equal(t, 1, about.Context())
+}
+
+func TestWalk(t *testing.T) {
+	tree := NewTree()
+	for _, pattern := range []string{"/b", "/a/{id}", "/a/{id:[0-9]+}", "/a/x", "/", "/a/{rest...}"} {
+		tree.Add(pattern, pattern)
+	}
+	var patterns []string
+	err := tree.Walk(func(node NodeInterface) error {
+		assert.Equal(t, node.FullPathPattern(), node.Context())
+		patterns = append(patterns, node.FullPathPattern())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/", "/a/x", "/a/{id:[0-9]+}", "/a/{id}", "/a/{rest...}", "/b"}, patterns)
+
+	stop := errors.New("stop")
+	count := 0
+	err = tree.Walk(func(node NodeInterface) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestLookupAppendShouldReuseBuffer(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/users/{id}/posts/{post}", 1)
+	buf := make([]Pair, 1, 4)
+	buf[0] = Pair{"host", "example"}
+	ctx, pairs, ok := tree.LookupAppend("/users/1/posts/2", false, buf)
+	assert.True(t, ok)
+	assert.Equal(t, 1, ctx)
+	assert.Equal(t, []Pair{{"host", "example"}, {"id", "1"}, {"post", "2"}}, pairs)
+	assert.Equal(t, &buf[0], &pairs[0])
+
+	_, pairs, ok = tree.LookupAppend("/users/1", false, buf)
+	assert.False(t, ok)
+	assert.Equal(t, []Pair{{"host", "example"}}, pairs)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.LookupAppend("/users/1/posts/2", false, buf[:0])
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestTryAddThenShouldReturnPatternError(t *testing.T) {
+	tree := NewTree()
+	cases := map[string]error{
+		"/path/to/{a}{b}/":    ErrInvalidPlaceholder,
+		"/static/{path...}/a": ErrInvalidPlaceholder,
+		"/path/to/{-abc}":     ErrInvalidParamName,
+		"/path/{c}/{c:.+}":    ErrDuplicateParam,
+		"/path/to/{:[ab}/":    ErrInvalidRegexp,
+		"/path/to/{param:}/":  ErrInvalidRegexp,
+	}
+	for pattern, expected := range cases {
+		_, err := tree.TryAddThen(pattern, nil)
+		if assert.IsType(t, (*PatternError)(nil), err, pattern) {
+			assert.Equal(t, pattern, err.(*PatternError).Pattern)
+			assert.True(t, errors.Is(err, expected), err.Error())
+		}
+	}
+
+	node, err := tree.TryAddThen("/users/{id:[0-9]+}", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/{id:[0-9]+}", node.FullPathPattern())
+	_, err = tree.TryAddThen("/users/{id:[0-9]+}", nil)
+	assert.NoError(t, err)
+	_, err = tree.TryAddThen("/users/{name:[0-9]+}", nil)
+	assert.True(t, errors.Is(err, ErrParamConflict))
+	_, err = tree.TryAddThen("/users/{uid:\\d+}", nil)
+	assert.True(t, errors.Is(err, ErrAmbiguousPattern))
+	_, err = tree.TryAddThen("/users/{name:[a-z]+}", nil)
+	assert.NoError(t, err)
+	_, err = tree.TryAddThen("/users/{id:int}", nil)
+	assert.True(t, errors.Is(err, ErrAmbiguousPattern))
+	_, err = tree.TryAddThen("/users/{name:[a-z]+}/{id:int}", nil)
+	assert.NoError(t, err)
+	_, err = tree.TryAddThen("/users/{name:[a-z]+}/{id:[0-9]+}", nil)
+	assert.True(t, errors.Is(err, ErrAmbiguousPattern))
+	_, err = tree.TryAddThen("/d/{a}-{b}", nil)
+	assert.NoError(t, err)
+	_, err = tree.TryAddThen("/d/{year:[0-9]{4}}-{month:[0-9]{2}}", nil)
+	assert.True(t, errors.Is(err, ErrAmbiguousPattern))
+	_, err = tree.TryAddThen("/d/{a}.{b}", nil)
+	assert.NoError(t, err)
+
+	//equivalent regexps are still accepted by AddThen
+	assert.NotPanics(t, func() { tree.Add("/posts/{id:[0-9]+}", 1) })
+	assert.NotPanics(t, func() { tree.Add("/posts/{uid:\\d+}", 2) })
+	assert.Panics(t, func() { tree.Add("/posts/{id:[0-9]+}/{id}", 3) })
+}
+
+func assertSameNode(t *testing.T, expected *node, actual *node) {
+	if !assert.NotNil(t, actual, expected.FullPathPattern()) {
+		return
+	}
+	assert.Equal(t, expected.path, actual.path)
+	assert.Equal(t, expected.pathLen, actual.pathLen)
+	assert.Equal(t, expected.nodeType, actual.nodeType, expected.path)
+	assert.Equal(t, expected.hasNonStatic, actual.hasNonStatic, expected.path)
+	assert.Equal(t, expected.FullPathPattern(), actual.FullPathPattern())
+	assert.Equal(t, expected.Context(), actual.Context(), expected.path)
+	assert.Equal(t, expected.Params(), actual.Params(), expected.path)
+	if actual.leaf != nil {
+		assert.Equal(t, actual, actual.leaf.node)
+	}
+	for i, child := range expected.staticBranches {
+		if child == nil {
+			assert.Nil(t, actual.staticBranches[i], expected.path+string(rune(i)))
+		} else {
+			assertSameNode(t, child, actual.staticBranches[i])
+			assert.Equal(t, actual, actual.staticBranches[i].parent)
+		}
+	}
+	if assert.Equal(t, len(expected.regexpBranches), len(actual.regexpBranches), expected.path) {
+		for i, child := range expected.regexpBranches {
+			assertSameNode(t, child, actual.regexpBranches[i])
+		}
+	}
+	for _, pair := range [][2]*node{{expected.wildBranch, actual.wildBranch}, {expected.catchAllBranch, actual.catchAllBranch}} {
+		if pair[0] == nil {
+			assert.Nil(t, pair[1], expected.path)
+		} else {
+			assertSameNode(t, pair[0], pair[1])
+		}
+	}
+}
+
+func TestRemoveShouldBuildSameTreeAsFresh(t *testing.T) {
+	patterns := []string{
+		"/",
+		"/users",
+		"/users/new",
+		"/users/{id:[0-9]+}",
+		"/users/{id:[0-9]+}/posts",
+		"/users/{name}",
+		"/users/{name}/profile",
+		"/usage",
+		"/static/{path...}",
+		"/static/css/app.css",
+		"/a/{x:[a-z]+}/{y}",
+		"/a/{x:[0-9]+}/{y}",
+	}
+	lookups := []string{"/", "/users", "/users/", "/users/new", "/users/12", "/users/12/posts", "/users/bob",
+		"/users/bob/profile", "/usage", "/use", "/static/css/app.css", "/static/css/b.css", "/static/x",
+		"/a/b/c", "/a/1/c", "/a/B/c", "/nothing"}
+
+	for i := range patterns {
+		for _, fixTailingSlash := range []bool{false, true} {
+			tree := NewTree()
+			fresh := NewTree()
+			for j, pattern := range patterns {
+				tree.Add(pattern, j)
+				if i != j {
+					fresh.Add(pattern, j)
+				}
+			}
+			assert.True(t, tree.Remove(patterns[i]), patterns[i])
+			assert.False(t, tree.Remove(patterns[i]), patterns[i])
+			assertSameNode(t, fresh, tree)
+			for _, lookup := range lookups {
+				expectedCtx, expectedPairs, expectedOk := fresh.Lookup(lookup, fixTailingSlash)
+				ctx, pairs, ok := tree.Lookup(lookup, fixTailingSlash)
+				assert.Equal(t, expectedOk, ok, patterns[i]+" "+lookup)
+				assert.Equal(t, expectedCtx, ctx, patterns[i]+" "+lookup)
+				assert.Equal(t, expectedPairs, pairs, patterns[i]+" "+lookup)
+			}
+		}
+	}
+
+	tree := NewTree()
+	for _, pattern := range patterns {
+		tree.Add(pattern, pattern)
+	}
+	for _, pattern := range patterns {
+		assert.True(t, tree.Remove(pattern), pattern)
+	}
+	assertSameNode(t, NewTree(), tree)
+	tree.Add("/users/{id}", 1)
+	assertFound(t, tree, "/users/1", false, 1)
+}
+
+func TestFindAndRemoveShouldMatchParamNames(t *testing.T) {
+	tree := NewTree()
+	tree.Add("/users/{id}/{*}", 1)
+	assert.Nil(t, tree.Find("/users/{name}/{}"))
+	assert.Nil(t, tree.Find("/users/{id}"))
+	assert.Nil(t, tree.Find("/posts"))
+	assert.Nil(t, tree.Find("/users/{id}/{:[a-z]+}"))
+	assert.Equal(t, 1, tree.Find("/users/{id}/{}").Context())
+	assert.False(t, tree.Remove("/users/{name}/{}"))
+	assert.False(t, tree.Remove("/users/{id}/{a}/b"))
+	assert.True(t, tree.Remove("/users/{id}/{*}"))
+	assertNotFound(t, tree, "/users/1/2", false)
+}
+
+func TestTryAddThenErrorShouldLeaveTreeUnchanged(t *testing.T) {
+	patterns := []string{"/users", "/users/{id:[0-9]+}/posts", "/users/{name}", "/static/{path...}"}
+	failing := []string{
+		"/usage/{a}{b}",
+		"/users/{id:[0-9]+}/posts/{x}/{x}",
+		"/users/{uid:\\d+}/comments",
+		"/users/{name}/files/{:[ab}",
+		"/users/{name}/{id:[0-9]+}/{f}.{x:[}",
+		"/users/{uid:[0-9]+}/posts",
+		"/u",
+	}
+	for _, pattern := range failing {
+		tree := NewTree()
+		fresh := NewTree()
+		for _, p := range patterns {
+			tree.Add(p, p)
+			fresh.Add(p, p)
+		}
+		tree.Add("/u/{id}", 1)
+		fresh.Add("/u/{id}", 1)
+		if pattern == "/u" {
+			pattern = "/u/{name}"
+		}
+		_, err := tree.TryAddThen(pattern, func(context interface{}) interface{} { return 2 })
+		assert.Error(t, err, pattern)
+		assertSameNode(t, fresh, tree)
+	}
+
+	tree := NewTree()
+	_, err := tree.TryAddThen("/{a}/{a}", nil)
+	assert.Error(t, err)
+	assertSameNode(t, NewTree(), tree)
+}
+
+func TestClone(t *testing.T) {
+	patterns := []string{"/", "/users", "/users/{id:[0-9]+}", "/users/{name}/profile", "/files/{name}.json",
+		"/static/{path...}", "/v{version:int}/items"}
+	tree := NewTree()
+	for i, pattern := range patterns {
+		tree.Add(pattern, i)
+	}
+	clone := tree.Clone(func(context interface{}) interface{} {
+		return context.(int) * 10
+	}).(*node)
+	for i, pattern := range patterns {
+		assert.Equal(t, i, tree.Find(pattern).Context())
+		assert.Equal(t, i*10, clone.Find(pattern).Context())
+	}
+	assertFoundParams(t, clone, "/users/bob/profile", false, []*Pair{{"name", "bob"}}, 30)
+	assertFoundParams(t, clone, "/v2/items", false, []*Pair{{"version", "2"}}, 60)
+
+	assert.True(t, clone.Remove("/users/{id:[0-9]+}"))
+	clone.Add("/usage", 7)
+	assertFound(t, tree, "/users/1", false, 2)
+	assertNotFound(t, tree, "/usage", false)
+	assertSameNode(t, tree, tree.Clone(nil).(*node))
+}
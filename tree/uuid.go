@@ -0,0 +1,55 @@
+package tree
+
+import "errors"
+
+// UUID is the value of "uuid" placeholders converted by its built-in converter
+type UUID [16]byte
+
+func (u UUID) String() string {
+	const hexDigits = "0123456789abcdef"
+	buf := make([]byte, 0, 36)
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			buf = append(buf, '-')
+		}
+		buf = append(buf, hexDigits[b>>4], hexDigits[b&0x0f])
+	}
+	return string(buf)
+}
+
+// ParseUUID parses UUID in canonical form "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+func ParseUUID(s string) (u UUID, err error) {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errors.New("invalid UUID '" + s + "'")
+	}
+	j := 0
+	for i := 0; i < len(s); i += 2 {
+		if s[i] == '-' {
+			i++
+		}
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return UUID{}, errors.New("invalid UUID '" + s + "'")
+		}
+		u[j] = hi<<4 | lo
+		j++
+	}
+	return u, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func convertUUID(s string) (interface{}, error) {
+	return ParseUUID(s)
+}